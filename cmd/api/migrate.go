@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"restapi/internal/migrations"
+	"strconv"
+)
+
+const migrateUsage = "usage: server migrate up | down | status | to <version>"
+
+// runMigrate handles the "migrate" subcommand
+func runMigrate(db *sql.DB, args []string) {
+	if len(args) == 0 {
+		log.Fatalln(migrateUsage)
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatalln("Error loading migrations", err)
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			log.Fatalln(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalln("Invalid migration version", args[1])
+		}
+		err = migrator.To(ctx, version)
+	case "status":
+		var statuses []migrations.Status
+		statuses, err = migrator.Status(ctx)
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-30s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		log.Fatalln(migrateUsage)
+	}
+
+	if err != nil {
+		log.Fatalln("Migration failed:", err)
+	}
+}
+
+// checkSchema refuses to start the server while migrations are pending
+func checkSchema(db *sql.DB) {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatalln("Error loading migrations", err)
+	}
+
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		log.Fatalln("Error checking the database schema", err)
+	}
+
+	if len(pending) > 0 {
+		log.Fatalf("Database schema is behind by %d migration(s), starting with %03d_%s. Run \"migrate up\" first.\n", len(pending), pending[0].Version, pending[0].Name)
+	}
+}
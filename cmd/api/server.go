@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"embed"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"restapi/internal/api/handlers"
 	mw "restapi/internal/api/middlewares"
 	"restapi/internal/api/routers"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/internal/repositories/sqlconnect"
 	"restapi/pkg/utils"
 	"time"
 
@@ -50,6 +56,27 @@ func loadEnvFromEmbeddedFile() {
 	}
 }
 
+// checkBaseURL fails unless base is an absolute http or https URL
+func checkBaseURL(base string) error {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("APP_BASE_URL must be the absolute URL of the API, got %q", base)
+	}
+	return nil
+}
+
+// applyEnrollments moves students into the class of their enrollment of the day, now and then
+// every interval, so a promotion takes effect on the first day of its term
+func applyEnrollments(enrollments repositories.EnrollmentRepository, interval time.Duration) {
+	for {
+		_, err := enrollments.ApplyOn(context.Background(), models.Today())
+		if err != nil {
+			log.Println("Error moving students into the class of their enrollment", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
 func main() {
 	// only in production for running source code
 	// err := godotenv.Load()
@@ -60,6 +87,37 @@ func main() {
 	// load environment variables from the embedded .env
 	loadEnvFromEmbeddedFile()
 
+	// login tokens and keyset cursors are signed, an empty key would let anyone forge them
+	if err := utils.CheckJWTSecret(); err != nil {
+		log.Fatalln(err)
+	}
+	if err := utils.CheckCursorSecret(); err != nil {
+		log.Fatalln(err)
+	}
+	if _, err := utils.TokenLifetime(); err != nil {
+		log.Fatalln("JWT_EXPIRES_IN is not a duration", err)
+	}
+
+	// one connection pool for the whole server, shared by every handler
+	db, err := sqlconnect.ConnectDb()
+	if err != nil {
+		log.Fatalln("Error connecting to the database", err)
+	}
+	defer db.Close()
+
+	// "server migrate ..." manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(db, os.Args[2:])
+		return
+	}
+
+	checkSchema(db)
+
+	// emailed links point at APP_BASE_URL, never at the Host a client sends
+	if err := checkBaseURL(os.Getenv("APP_BASE_URL")); err != nil {
+		log.Fatalln(err)
+	}
+
 	fmt.Println("environment variable CERT_FILE", os.Getenv("CERT_FILE"))
 
 	port := os.Getenv("SERVER_PORT")
@@ -80,17 +138,19 @@ func main() {
 		CheckQuery:                  true,
 		CheckBody:                   true,
 		CheckBodyOnlyForContentType: "application/x-www-form-urlencoded",
-		Whitelist:                   []string{"sortBy", "sortOrder", "name", "age", "class"},
+		Whitelist:                   []string{"sortBy", "sortOrder", "sortby", "id", "name", "age", "class", "first_name", "last_name", "email", "subject", "username", "role", "page", "limit", "cursor", "q", "fields", "mode", "include_deleted", "class_id", "grade_level", "room", "capacity", "homeroom_teacher_id", "academic_year_id", "start_date", "end_date", "from", "to"},
 	}
 
 	// secureMux := mw.Hpp(hppOptions)(rl.Middleware(mw.Compression(mw.ResponseTimeMiddleware(mw.SecurityHeaders(mw.Cors(mux))))))
 	// secureMux := jwtMiddleware(mw.SecurityHeaders(router))
 	// secureMux := mw.SecurityHeaders(router)
 
-	router := routers.MainRouter()
-	jwtMiddleware := mw.MiddlewaresExcludePaths(mw.JWTMiddleware, "/execs/login", "/execs/forgotpassword", "/execs/resetpassword/reset")
+	h := handlers.NewHandler(db, utils.NewMailer())
+	go applyEnrollments(h.Enrollments, time.Hour)
+	router := routers.MainRouter(h)
+	jwtMiddleware := mw.MiddlewaresExcludePaths(mw.JWTMiddleware, "/execs/login", "/execs/forgotpassword", "/execs/resetpassword/reset/")
 
-	secureMux := utils.ApplyMiddlewares(router, mw.SecurityHeaders, mw.Compression, mw.Hpp(hppOptions), jwtMiddleware, mw.ResponseTimeMiddleware, rl.Middleware, mw.Cors)
+	secureMux := utils.ApplyMiddlewares(router, mw.SecurityHeaders, mw.Compression, mw.Hpp(hppOptions), jwtMiddleware, mw.ResponseTimeMiddleware, rl.Middleware, mw.Cors, mw.RequestID)
 
 	// create custom server
 	server := &http.Server{
@@ -100,7 +160,7 @@ func main() {
 	}
 
 	fmt.Println("Server is running on port", port)
-	err := server.ListenAndServeTLS(cert, key)
+	err = server.ListenAndServeTLS(cert, key)
 	if err != nil {
 		log.Fatalln("Error starting the server", err)
 	}
@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+)
+
+// academicYearFilterFields declares the columns academic years can be filtered on and the
+// operators each one allows. Dates compare as days, so they take the number operators.
+var academicYearFilterFields = utils.FilterFields{
+	"id":         utils.NumberOperators,
+	"name":       utils.TextOperators,
+	"start_date": utils.NumberOperators,
+	"end_date":   utils.NumberOperators,
+}
+
+// termFilterFields declares the columns terms can be filtered on and the operators each one allows
+var termFilterFields = utils.FilterFields{
+	"id":               utils.NumberOperators,
+	"academic_year_id": utils.NumberOperators,
+	"name":             utils.TextOperators,
+	"start_date":       utils.NumberOperators,
+	"end_date":         utils.NumberOperators,
+}
+
+// GET /academic-years
+func (h *Handler) GetAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	listCatalog(w, r, h.AcademicYears, academicYearFilterFields)
+}
+
+// GET /academic-years/{id}
+func (h *Handler) GetOneAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	getCatalog(w, r, h.AcademicYears, "Academic Year")
+}
+
+// POST /academic-years
+func (h *Handler) AddAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	addCatalog(w, r, h.AcademicYears, h.validateAcademicYear)
+}
+
+// PUT /academic-years/{id} answers 400 when the new dates leave a term of the year outside them
+func (h *Handler) UpdateAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, h.AcademicYears, "Academic Year", func(ctx context.Context, year *models.AcademicYear) ([]utils.FieldError, error) {
+		invalid, err := h.validateAcademicYear(ctx, year)
+		if err != nil || len(invalid) > 0 {
+			return invalid, err
+		}
+		return h.checkYearTerms(ctx, *year)
+	}, func(year *models.AcademicYear, id int) { year.ID = id })
+}
+
+// DELETE /academic-years/{id} answers 409 while the year has terms
+func (h *Handler) DeleteOneAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	deleteCatalog(w, r, h.AcademicYears, "Academic Year")
+}
+
+// GET /academic-years/{id}/terms lists the terms of the academic year
+func (h *Handler) GetAcademicYearTermsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Academic Year ID"))
+		return
+	}
+
+	_, err = h.AcademicYears.Get(r.Context(), id, "id")
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Academic Year not found"))
+		return
+	}
+
+	listCatalog(w, r, h.Terms, termFilterFields, utils.Filter{Field: "academic_year_id", Operator: utils.OpEq, Value: strconv.Itoa(id)})
+}
+
+// GET /terms
+func (h *Handler) GetTermsHandler(w http.ResponseWriter, r *http.Request) {
+	listCatalog(w, r, h.Terms, termFilterFields)
+}
+
+// GET /terms/{id}
+func (h *Handler) GetOneTermHandler(w http.ResponseWriter, r *http.Request) {
+	getCatalog(w, r, h.Terms, "Term")
+}
+
+// POST /terms
+func (h *Handler) AddTermsHandler(w http.ResponseWriter, r *http.Request) {
+	addCatalog(w, r, h.Terms, h.validateTerm)
+}
+
+// PUT /terms/{id} answers 400 when the new dates leave an enrollment in the term outside them
+func (h *Handler) UpdateTermHandler(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, h.Terms, "Term", func(ctx context.Context, term *models.Term) ([]utils.FieldError, error) {
+		invalid, err := h.validateTerm(ctx, term)
+		if err != nil || len(invalid) > 0 {
+			return invalid, err
+		}
+		return h.checkTermEnrollments(ctx, *term)
+	}, func(term *models.Term, id int) { term.ID = id })
+}
+
+// DELETE /terms/{id} answers 409 while students are enrolled in the term
+func (h *Handler) DeleteOneTermHandler(w http.ResponseWriter, r *http.Request) {
+	deleteCatalog(w, r, h.Terms, "Term")
+}
+
+// validateAcademicYear checks the validate tags of a new or replaced academic year and its dates
+func (h *Handler) validateAcademicYear(ctx context.Context, year *models.AcademicYear) ([]utils.FieldError, error) {
+	return append(utils.Validate(year), checkPeriod(year.StartDate, year.EndDate)...), nil
+}
+
+// checkYearTerms reports new dates of year that leave one of its terms outside them
+func (h *Handler) checkYearTerms(ctx context.Context, year models.AcademicYear) ([]utils.FieldError, error) {
+	terms, err := h.Terms.List(ctx, repositories.ListOptions{
+		Filters: []utils.Filter{{Field: "academic_year_id", Operator: utils.OpEq, Value: strconv.Itoa(year.ID)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, term := range terms {
+		if term.StartDate.Before(year.StartDate.Time) || term.EndDate.After(year.EndDate.Time) {
+			return []utils.FieldError{{Field: "start_date", Rule: "within", Message: "must keep the terms of the academic year within its dates"}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkTermEnrollments reports new dates of term that leave one of its enrollments outside them
+func (h *Handler) checkTermEnrollments(ctx context.Context, term models.Term) ([]utils.FieldError, error) {
+	enrollments, err := h.Enrollments.ForTerm(ctx, term.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, enrollment := range enrollments {
+		if enrollment.StartDate.Before(term.StartDate.Time) || enrollment.EndDate.After(term.EndDate.Time) {
+			return []utils.FieldError{{Field: "start_date", Rule: "within", Message: "must keep the enrollments of the term within its dates"}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateTerm checks the validate tags of a new or replaced term, and that its dates lie within
+// an academic year that exists
+func (h *Handler) validateTerm(ctx context.Context, term *models.Term) ([]utils.FieldError, error) {
+	invalid := append(utils.Validate(term), checkPeriod(term.StartDate, term.EndDate)...)
+	if term.AcademicYearID == 0 || len(invalid) > 0 {
+		return invalid, nil
+	}
+
+	year, err := h.AcademicYears.Get(ctx, term.AcademicYearID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return []utils.FieldError{{Field: "academic_year_id", Rule: "exists", Message: "is not a known academic year"}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return checkWithin(term.StartDate, term.EndDate, year.StartDate, year.EndDate, "the academic year"), nil
+}
+
+// checkPeriod reports an end date before the start date. Missing dates are left to required.
+func checkPeriod(start, end models.Date) []utils.FieldError {
+	if start.IsZero() || end.IsZero() || !end.Before(start.Time) {
+		return nil
+	}
+	return []utils.FieldError{{Field: "end_date", Rule: "after", Message: "must not be before start_date"}}
+}
+
+// checkWithin reports the dates of start and end that lie outside the period from from to to
+func checkWithin(start, end, from, to models.Date, period string) []utils.FieldError {
+	var invalid []utils.FieldError
+	if start.Before(from.Time) || start.After(to.Time) {
+		invalid = append(invalid, utils.FieldError{Field: "start_date", Rule: "within", Message: "must lie within " + period})
+	}
+	if end.Before(from.Time) || end.After(to.Time) {
+		invalid = append(invalid, utils.FieldError{Field: "end_date", Rule: "within", Message: "must lie within " + period})
+	}
+	return invalid
+}
+
+// listCatalog writes a page of the records of repo, narrowed to scope
+func listCatalog[T any](w http.ResponseWriter, r *http.Request, repo repositories.CatalogRepository[T], filterFields utils.FilterFields, scope ...utils.Filter) {
+	var model T
+	filters, err := utils.ParseFilters(r, filterFields)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	filters = append(filters, scope...)
+
+	fields, err := ParseFieldSet(r, model)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, DbFieldNames(model)),
+		Page:    page,
+		Limit:   limit,
+		Fields:  fields,
+	}
+
+	keyset, err := keysetOptions(r, &opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	list, err := repo.List(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if keyset {
+		writeKeysetPage(w, r, opts, list)
+		return
+	}
+
+	total, err := repo.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
+	response := struct {
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		Limit      int         `json:"limit"`
+		TotalPages int         `json:"total_pages"`
+		Data       interface{} `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(list),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       projectList(list, fields),
+	}
+
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	writeConditional(w, r, "", nil, response)
+}
+
+// getCatalog writes the record of repo named by the id path value
+func getCatalog[T any](w http.ResponseWriter, r *http.Request, repo repositories.CatalogRepository[T], noun string) {
+	var model T
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid "+noun+" ID"))
+		return
+	}
+
+	fields, err := ParseFieldSet(r, model)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	record, err := repo.Get(r.Context(), id, fields...)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, noun+" not found"))
+		return
+	}
+
+	writeConditional(w, r, "", nil, projectFields(record, fields))
+}
+
+// addCatalog creates the array of records in the body, all of them or none
+func addCatalog[T any](w http.ResponseWriter, r *http.Request, repo repositories.CatalogRepository[T], validate func(context.Context, *T) ([]utils.FieldError, error)) {
+	var model T
+	var newRecords []T
+	var rawRecords []map[string]interface{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
+		return
+	}
+
+	defer r.Body.Close()
+
+	err = json.Unmarshal(body, &rawRecords)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	fields := CheckFieldNames(model)
+	for _, record := range rawRecords {
+		for key := range record {
+			if !utils.ContainsString(fields, key) {
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
+				return
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, &newRecords)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	for i := range newRecords {
+		invalid, err := validate(r.Context(), &newRecords[i])
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
+	}
+	err = utils.ValidationFailed(fieldErrors)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	added, err := repo.Create(r.Context(), newRecords)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string `json:"status"`
+		Count  int    `json:"count"`
+		Data   []T    `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(added),
+		Data:   added,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// updateCatalog replaces the record of repo named by the id path value with the body
+func updateCatalog[T any](w http.ResponseWriter, r *http.Request, repo repositories.CatalogRepository[T], noun string, validate func(context.Context, *T) ([]utils.FieldError, error), setID func(*T, int)) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid "+noun+" ID"))
+		return
+	}
+
+	var updated T
+	err = json.NewDecoder(r.Body).Decode(&updated)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+
+	setID(&updated, id)
+	invalid, err := validate(r.Context(), &updated)
+	if err == nil {
+		err = utils.ValidationFailed(invalid)
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	updated, err = repo.Update(r.Context(), updated)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, noun+" not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// deleteCatalog deletes the record of repo named by the id path value
+func deleteCatalog[T any](w http.ResponseWriter, r *http.Request, repo repositories.CatalogRepository[T], noun string) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid "+noun+" ID"))
+		return
+	}
+
+	err = repo.Delete(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, noun+" not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
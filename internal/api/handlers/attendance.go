@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+)
+
+// POST /classes/{id}/attendance takes the roll-call of the class for a day, today unless date is
+// sent. Every record names a student of the class, and taking the roll-call again replaces the
+// records of the students it lists. Only a teacher of the class can take it and is kept as
+// recorded_by.
+func (h *Handler) RecordClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.pathClass(w, r)
+	if !ok {
+		return
+	}
+
+	recordedBy, err := h.recorder(r, class)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	var rollCall struct {
+		Date    models.Date         `json:"date"`
+		Records []models.Attendance `json:"records"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&rollCall)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+	if len(rollCall.Records) == 0 {
+		utils.WriteError(w, r, utils.Validation("records must list at least one student"))
+		return
+	}
+
+	if rollCall.Date.IsZero() {
+		rollCall.Date = models.Today()
+	}
+	var fieldErrors []utils.FieldError
+	if rollCall.Date.After(models.Today().Time) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "date", Rule: "past", Message: "must not be in the future"})
+	}
+
+	seen := make(map[int]bool)
+	for i := range rollCall.Records {
+		record := &rollCall.Records[i]
+		record.ID, record.ClassID, record.Date, record.RecordedBy = 0, class.ID, rollCall.Date, &recordedBy
+
+		invalid, err := h.checkAttendance(r.Context(), *record, seen)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
+	}
+	err = utils.ValidationFailed(fieldErrors)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	recorded, err := h.Attendance.Record(r.Context(), rollCall.Records)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Date   models.Date         `json:"date"`
+		Data   []models.Attendance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(recorded),
+		Date:   rollCall.Date,
+		Data:   recorded,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// checkAttendance checks the validate tags of a roll-call record and that it names a live student
+// of its class that seen does not hold yet, adding the student to seen
+func (h *Handler) checkAttendance(ctx context.Context, record models.Attendance, seen map[int]bool) ([]utils.FieldError, error) {
+	invalid := utils.Validate(record)
+	if record.StudentID == 0 {
+		return invalid, nil
+	}
+	if seen[record.StudentID] {
+		return append(invalid, utils.FieldError{Field: "student_id", Rule: "unique", Message: "is listed more than once"}), nil
+	}
+	seen[record.StudentID] = true
+
+	student, err := h.Students.Get(ctx, record.StudentID, false, "class_id")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return append(invalid, utils.FieldError{Field: "student_id", Rule: "exists", Message: "is not a known student"}), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if student.ClassID != record.ClassID {
+		invalid = append(invalid, utils.FieldError{Field: "student_id", Rule: "member", Message: "is not a student of the class"})
+	}
+	return invalid, nil
+}
+
+// GET /students/{id}/attendance lists the attendance of the student by day, between the from and
+// to query params when they are sent
+func (h *Handler) GetStudentAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.studentAttendanceQuery(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.Attendance.List(r.Context(), q)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Attendance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(list),
+		Data:   list,
+	}
+	writeConditional(w, r, "", nil, response)
+}
+
+// GET /students/{id}/attendance/summary counts the days of the student by status, across all of
+// its classes, and gives its attendance rate
+func (h *Handler) GetStudentAttendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.studentAttendanceQuery(w, r)
+	if !ok {
+		return
+	}
+
+	summaries, err := h.Attendance.Summarize(r.Context(), q)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	summary := models.AttendanceSummary{StudentID: q.StudentID}
+	if len(summaries) > 0 {
+		summary = summaries[0]
+	}
+	writeConditional(w, r, "", nil, summary)
+}
+
+// GET /classes/{id}/attendance/summary gives the attendance rate of the class and of each student
+// with attendance in it, also the ones that have since left
+func (h *Handler) GetClassAttendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.pathClass(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	summaries, err := h.Attendance.Summarize(r.Context(), repositories.AttendanceQuery{ClassID: class.ID, From: from, To: to})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	var total models.AttendanceSummary
+	for _, summary := range summaries {
+		total.Add(summary)
+	}
+
+	response := struct {
+		Status  string                     `json:"status"`
+		ClassID int                        `json:"class_id"`
+		Total   models.AttendanceSummary   `json:"total"`
+		Count   int                        `json:"count"`
+		Data    []models.AttendanceSummary `json:"data"`
+	}{
+		Status:  "success",
+		ClassID: class.ID,
+		Total:   total,
+		Count:   len(summaries),
+		Data:    summaries,
+	}
+	writeConditional(w, r, "", nil, response)
+}
+
+// studentAttendanceQuery reads the student named by the id path value and the date range,
+// writing the error response when it fails
+func (h *Handler) studentAttendanceQuery(w http.ResponseWriter, r *http.Request) (repositories.AttendanceQuery, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
+		return repositories.AttendanceQuery{}, false
+	}
+
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return repositories.AttendanceQuery{}, false
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return repositories.AttendanceQuery{}, false
+	}
+
+	_, err = h.Students.Get(r.Context(), id, withDeleted, "id")
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Student not found"))
+		return repositories.AttendanceQuery{}, false
+	}
+	return repositories.AttendanceQuery{StudentID: id, From: from, To: to}, true
+}
+
+// parseDateRange reads the from and to query params, either of which may be left out
+func parseDateRange(r *http.Request) (models.Date, models.Date, error) {
+	var dates [2]models.Date
+	for i, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		date, err := models.ParseDate(value)
+		if err != nil {
+			return models.Date{}, models.Date{}, utils.Validation(param + " must be a date like " + models.DateLayout)
+		}
+		dates[i] = date
+	}
+
+	from, to := dates[0], dates[1]
+	if !from.IsZero() && !to.IsZero() && to.Before(from.Time) {
+		return models.Date{}, models.Date{}, utils.Validation("to must not be before from")
+	}
+	return from, to, nil
+}
+
+// recorder returns the id of the teacher of class whose login token made the request. Logins are
+// execs, so that is the teacher with the email of the exec, and it teaches the class when the
+// class is its own or it is the homeroom teacher. Anyone else is forbidden.
+func (h *Handler) recorder(r *http.Request, class models.Class) (int, error) {
+	email, ok := r.Context().Value(utils.ContextKey("email")).(string)
+	if !ok || email == "" {
+		return 0, utils.Unauthorized("the login token does not name a user")
+	}
+
+	teachers, err := h.Teachers.List(r.Context(), repositories.ListOptions{
+		Filters: []utils.Filter{{Field: "email", Operator: utils.OpEq, Value: email}},
+		Fields:  []string{"id", "class_id"},
+		Page:    1,
+		Limit:   1,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(teachers) == 0 {
+		return 0, utils.Forbidden("only a teacher of the class can take its roll-call")
+	}
+
+	teacher := teachers[0]
+	homeroom := class.HomeroomTeacherID != nil && *class.HomeroomTeacherID == teacher.ID
+	if teacher.ClassID != class.ID && !homeroom {
+		return 0, utils.Forbidden("only a teacher of the class can take its roll-call")
+	}
+	return teacher.ID, nil
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+)
+
+// classFilterFields declares the columns classes can be filtered on and the operators each one allows
+var classFilterFields = utils.FilterFields{
+	"id":                  utils.NumberOperators,
+	"name":                utils.TextOperators,
+	"grade_level":         utils.NumberOperators,
+	"room":                utils.TextOperators,
+	"capacity":            append([]string{utils.OpNull}, utils.NumberOperators...),
+	"homeroom_teacher_id": append([]string{utils.OpNull}, utils.NumberOperators...),
+}
+
+func (h *Handler) GetClassesHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := utils.ParseFilters(r, classFilterFields)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	fields, err := ParseFieldSet(r, models.Class{})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, DbFieldNames(models.Class{})),
+		Page:    page,
+		Limit:   limit,
+		Fields:  fields,
+	}
+
+	keyset, err := keysetOptions(r, &opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	classList, err := h.Classes.List(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if keyset {
+		writeKeysetPage(w, r, opts, classList)
+		return
+	}
+
+	total, err := h.Classes.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
+	response := struct {
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		Limit      int         `json:"limit"`
+		TotalPages int         `json:"total_pages"`
+		Data       interface{} `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(classList),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       projectList(classList, fields),
+	}
+
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	writeConditional(w, r, "", nil, response)
+}
+
+// GET /classes/{id}. Classes are not versioned, so the ETag is computed from the body.
+func (h *Handler) GetOneClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Class ID"))
+		return
+	}
+
+	fields, err := ParseFieldSet(r, models.Class{})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	class, err := h.Classes.Get(r.Context(), id, fields...)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Class not found"))
+		return
+	}
+
+	writeConditional(w, r, "", nil, projectFields(class, fields))
+}
+
+// POST /classes
+func (h *Handler) AddClassesHandler(w http.ResponseWriter, r *http.Request) {
+	var newClasses []models.Class
+	var rawClasses []map[string]interface{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
+		return
+	}
+
+	defer r.Body.Close()
+
+	err = json.Unmarshal(body, &rawClasses)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	fields := CheckFieldNames(models.Class{})
+	for _, class := range rawClasses {
+		for key := range class {
+			if !utils.ContainsString(fields, key) {
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
+				return
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, &newClasses)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	for i := range newClasses {
+		invalid, err := h.validateClass(r.Context(), &newClasses[i])
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
+	}
+	err = utils.ValidationFailed(fieldErrors)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	addedClasses, err := h.Classes.Create(r.Context(), newClasses)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedClasses),
+		Data:   addedClasses,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// PUT /classes/{id}
+func (h *Handler) UpdateClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Class ID"))
+		return
+	}
+
+	var updatedClass models.Class
+	err = json.NewDecoder(r.Body).Decode(&updatedClass)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+
+	invalid, err := h.validateClass(r.Context(), &updatedClass)
+	if err == nil {
+		err = utils.ValidationFailed(invalid)
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	updatedClass.ID = id
+	updatedClass, err = h.Classes.Update(r.Context(), updatedClass)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Class not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedClass)
+}
+
+// PATCH /classes/{id}
+func (h *Handler) PatchOneClassHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Class ID"))
+		return
+	}
+
+	patch, err := decodePatch[models.Class](r, id, 0)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	patch.Prepare = func(model interface{}, changed []string) error {
+		if !utils.ContainsString(changed, "homeroom_teacher_id") {
+			return nil
+		}
+		invalid, err := h.checkHomeroomTeacher(r.Context(), model.(*models.Class))
+		if err != nil {
+			return err
+		}
+		return utils.ValidationFailed(invalid)
+	}
+
+	updatedClasses, err := h.Classes.Patch(r.Context(), []repositories.Patch{patch})
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Class not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedClasses[0])
+}
+
+// DELETE /classes/{id} answers 409 while teachers or students are still in the class
+func (h *Handler) DeleteOneClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Class ID"))
+		return
+	}
+
+	err = h.Classes.Delete(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Class not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /classes/{id}/teachers lists the teachers of the class
+func (h *Handler) GetClassTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.pathClass(w, r)
+	if !ok {
+		return
+	}
+	h.listTeachers(w, r, classFilter(class.ID))
+}
+
+// GET /classes/{id}/students lists the students in the class
+func (h *Handler) GetClassStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.pathClass(w, r)
+	if !ok {
+		return
+	}
+	h.listStudents(w, r, classFilter(class.ID))
+}
+
+// pathClass reads the class named by the id path value, writing the error response when it fails
+func (h *Handler) pathClass(w http.ResponseWriter, r *http.Request) (models.Class, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Class ID"))
+		return models.Class{}, false
+	}
+
+	class, err := h.Classes.Get(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Class not found"))
+		return models.Class{}, false
+	}
+	return class, true
+}
+
+// validateClass checks the validate tags of a new or replaced class and its homeroom teacher
+func (h *Handler) validateClass(ctx context.Context, class *models.Class) ([]utils.FieldError, error) {
+	invalid, err := h.checkHomeroomTeacher(ctx, class)
+	if err != nil {
+		return nil, err
+	}
+	return append(utils.Validate(class), invalid...), nil
+}
+
+// checkHomeroomTeacher reports a homeroom teacher that does not exist or is deleted
+func (h *Handler) checkHomeroomTeacher(ctx context.Context, class *models.Class) ([]utils.FieldError, error) {
+	if class.HomeroomTeacherID == nil {
+		return nil, nil
+	}
+
+	_, err := h.Teachers.Get(ctx, *class.HomeroomTeacherID, false, "id")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return []utils.FieldError{{Field: "homeroom_teacher_id", Rule: "exists", Message: "is not a known teacher"}}, nil
+	}
+	return nil, err
+}
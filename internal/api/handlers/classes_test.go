@@ -0,0 +1,54 @@
+package handlers_test
+
+import (
+	"net/http"
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestClassCapacity(t *testing.T) {
+	server := newServer(t, false)
+
+	// a capacity is optional, but 0 is not one
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "10A", "grade_level": 10, "capacity": 0}]`), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "10A", "grade_level": 10, "capacity": 201}]`), http.StatusBadRequest, nil)
+
+	var added page[models.Class]
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "10A", "grade_level": 10}, {"name": "10B", "grade_level": 10, "capacity": 25}]`), http.StatusCreated, &added)
+	if added.Data[0].Capacity != nil || added.Data[1].Capacity == nil || *added.Data[1].Capacity != 25 {
+		t.Fatalf("got %+v", added.Data)
+	}
+
+	var class models.Class
+	expect(t, send(t, server, "PATCH", "/classes/4", `{"capacity": null}`), http.StatusOK, &class)
+	if class.Capacity != nil {
+		t.Errorf("got the capacity %d", *class.Capacity)
+	}
+	expect(t, send(t, server, "PATCH", "/classes/4", `{"capacity": 0}`), http.StatusBadRequest, nil)
+
+	var list page[models.Class]
+	expect(t, send(t, server, "GET", "/classes?capacity[null]=true", ""), http.StatusOK, &list)
+	if list.Total != 4 {
+		t.Errorf("got %d classes without a capacity, want 4", list.Total)
+	}
+}
+
+func TestClassConflicts(t *testing.T) {
+	server := newServer(t, false)
+
+	// names are unique, case insensitively
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "9a", "grade_level": 9}]`), http.StatusConflict, nil)
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "10A", "grade_level": 10}, {"name": "10a", "grade_level": 10}]`), http.StatusConflict, nil)
+	expect(t, send(t, server, "PATCH", "/classes/2", `{"name": "9A"}`), http.StatusConflict, nil)
+
+	// a class with members, deleted ones too, can't be deleted. 9B is left with deleted teacher 2.
+	addTeachers(t, server)
+	expect(t, send(t, server, "PATCH", "/teachers/4", `{"class_id": 1}`), http.StatusOK, nil)
+	expect(t, send(t, server, "DELETE", "/teachers/2", ""), http.StatusNoContent, nil)
+	expect(t, send(t, server, "DELETE", "/classes/2", ""), http.StatusConflict, nil)
+	expect(t, send(t, server, "DELETE", "/classes/1", ""), http.StatusConflict, nil)
+
+	expect(t, send(t, server, "POST", "/classes", `[{"name": "10A", "grade_level": 10}]`), http.StatusCreated, nil)
+	expect(t, send(t, server, "DELETE", "/classes/3", ""), http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/classes/3", ""), http.StatusNotFound, nil)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+)
+
+// GET /students/{id}/enrollments lists the classes the student was in, term by term, oldest first
+func (h *Handler) GetStudentEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
+		return
+	}
+
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	_, err = h.Students.Get(r.Context(), id, withDeleted, "id")
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Student not found"))
+		return
+	}
+
+	enrollments, err := h.Enrollments.ForStudent(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollments),
+		Data:   enrollments,
+	}
+	writeConditional(w, r, "", nil, response)
+}
+
+// POST /students/{id}/enrollments records the class of the student for a term, or part of it,
+// mostly to fill in past terms. The dates default to those of the term and must not overlap
+// another enrollment of the student. An enrollment that covers today also moves the student into
+// its class. PUT or PATCH on the student with a new class record the move in the enrollments of
+// the current term.
+func (h *Handler) AddStudentEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
+		return
+	}
+
+	var enrollment models.Enrollment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&enrollment)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+
+	_, err = h.Students.Get(r.Context(), id, false, "id")
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Student not found"))
+		return
+	}
+	enrollment.ID, enrollment.StudentID = 0, id
+
+	invalid, err := h.validateEnrollment(r.Context(), &enrollment)
+	if err == nil {
+		err = utils.ValidationFailed(invalid)
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	enrollment, err = h.Enrollments.Create(r.Context(), enrollment)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// validateEnrollment checks the validate tags of a new enrollment, that its term and class exist,
+// and fills in the dates of the term for the ones left out
+func (h *Handler) validateEnrollment(ctx context.Context, enrollment *models.Enrollment) ([]utils.FieldError, error) {
+	invalid := utils.Validate(enrollment)
+	if len(invalid) > 0 {
+		return invalid, nil
+	}
+
+	_, err := h.Classes.Get(ctx, enrollment.ClassID, "id")
+	if errors.Is(err, repositories.ErrNotFound) {
+		invalid = append(invalid, utils.FieldError{Field: "class_id", Rule: "exists", Message: "is not a known class"})
+	} else if err != nil {
+		return nil, err
+	}
+
+	term, err := h.Terms.Get(ctx, enrollment.TermID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return append(invalid, utils.FieldError{Field: "term_id", Rule: "exists", Message: "is not a known term"}), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if enrollment.StartDate.IsZero() {
+		enrollment.StartDate = term.StartDate
+	}
+	if enrollment.EndDate.IsZero() {
+		enrollment.EndDate = term.EndDate
+	}
+	invalid = append(invalid, checkPeriod(enrollment.StartDate, enrollment.EndDate)...)
+	return append(invalid, checkWithin(enrollment.StartDate, enrollment.EndDate, term.StartDate, term.EndDate, "the term")...), nil
+}
+
+// POST /classes/{id}/promote enrolls every student of the class in to_class_id for term_id, all
+// of them or none. Without term_id it is the next term, the first one that starts after today;
+// a term that has started is refused, students move during a term with PUT or PATCH. The students
+// keep their class until the term starts, when the server moves them.
+func (h *Handler) PromoteClassHandler(w http.ResponseWriter, r *http.Request) {
+	from, ok := h.pathClass(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		ToClassID int `json:"to_class_id"`
+		TermID    int `json:"term_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+
+	to, invalid, err := h.promotionClass(r.Context(), from, request.ToClassID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	term, termInvalid, err := h.promotionTerm(r.Context(), request.TermID)
+	if err == nil {
+		err = utils.ValidationFailed(append(invalid, termInvalid...))
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	enrollments, err := h.Enrollments.Promote(r.Context(), from.ID, to, term)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status   string              `json:"status"`
+		Promoted int                 `json:"promoted"`
+		TermID   int                 `json:"term_id"`
+		Data     []models.Enrollment `json:"data"`
+	}{
+		Status:   "success",
+		Promoted: len(enrollments),
+		TermID:   term.ID,
+		Data:     enrollments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// promotionClass reads the class students are promoted to, which must be another one than from
+func (h *Handler) promotionClass(ctx context.Context, from models.Class, toClassID int) (models.Class, []utils.FieldError, error) {
+	if toClassID == 0 {
+		return models.Class{}, []utils.FieldError{{Field: "to_class_id", Rule: "required", Message: "is required"}}, nil
+	}
+	if toClassID == from.ID {
+		return models.Class{}, []utils.FieldError{{Field: "to_class_id", Rule: "different", Message: "must be another class"}}, nil
+	}
+
+	to, err := h.Classes.Get(ctx, toClassID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Class{}, []utils.FieldError{{Field: "to_class_id", Rule: "exists", Message: "is not a known class"}}, nil
+	}
+	return to, nil, err
+}
+
+// promotionTerm reads the term students are promoted for, which must start after today, the next
+// one when termID is 0
+func (h *Handler) promotionTerm(ctx context.Context, termID int) (models.Term, []utils.FieldError, error) {
+	if termID != 0 {
+		term, err := h.Terms.Get(ctx, termID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return models.Term{}, []utils.FieldError{{Field: "term_id", Rule: "exists", Message: "is not a known term"}}, nil
+		} else if err != nil {
+			return models.Term{}, nil, err
+		}
+		if !term.StartDate.After(models.Today().Time) {
+			return models.Term{}, []utils.FieldError{{Field: "term_id", Rule: "future", Message: "must start after today"}}, nil
+		}
+		return term, nil, nil
+	}
+
+	terms, err := h.Terms.List(ctx, repositories.ListOptions{
+		Filters: []utils.Filter{{Field: "start_date", Operator: utils.OpGt, Value: models.Today().String()}},
+		Sort:    []utils.SortField{{Field: "start_date", Order: "asc"}},
+		Page:    1,
+		Limit:   1,
+	})
+	if err != nil {
+		return models.Term{}, nil, err
+	}
+	if len(terms) == 0 {
+		return models.Term{}, []utils.FieldError{{Field: "term_id", Rule: "required", Message: "is required, no term starts after today"}}, nil
+	}
+	return terms[0], nil, nil
+}
@@ -0,0 +1,66 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestPromoteClass(t *testing.T) {
+	h := newHandler(t, false)
+	server := serve(h)
+	addTerm(t, server)
+	today := models.Today()
+
+	// the next term, in the next academic year
+	start, end := models.Date{Time: today.AddDate(0, 0, 31)}, models.Date{Time: today.AddDate(0, 0, 90)}
+	body := fmt.Sprintf(`[{"name": "next", "start_date": "%s", "end_date": "%s"}]`, start, end)
+	expect(t, send(t, server, "POST", "/academic-years", body), http.StatusCreated, nil)
+	body = fmt.Sprintf(`[{"academic_year_id": 2, "name": "Spring", "start_date": "%s", "end_date": "%s"}]`, start, end)
+	expect(t, send(t, server, "POST", "/terms", body), http.StatusCreated, nil)
+
+	expect(t, send(t, server, "POST", "/students", `[
+		{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9A"},
+		{"first_name": "Ben", "last_name": "Lee", "email": "ben.lee@school.test", "class": "9A"}
+	]`), http.StatusCreated, nil)
+
+	// the term of the day has started
+	expect(t, send(t, server, "POST", "/classes/1/promote", `{"to_class_id": 2, "term_id": 1}`), http.StatusBadRequest, nil)
+
+	expect(t, send(t, server, "PATCH", "/classes/2", `{"capacity": 1}`), http.StatusOK, nil)
+	expect(t, send(t, server, "POST", "/classes/1/promote", `{"to_class_id": 2}`), http.StatusConflict, nil)
+	expect(t, send(t, server, "PATCH", "/classes/2", `{"capacity": 30}`), http.StatusOK, nil)
+
+	var promoted struct {
+		Promoted int                 `json:"promoted"`
+		TermID   int                 `json:"term_id"`
+		Data     []models.Enrollment `json:"data"`
+	}
+	expect(t, send(t, server, "POST", "/classes/1/promote", `{"to_class_id": 2}`), http.StatusOK, &promoted)
+	if promoted.Promoted != 2 || promoted.TermID != 2 || !promoted.Data[0].StartDate.Equal(start.Time) {
+		t.Fatalf("got %+v", promoted)
+	}
+	expect(t, send(t, server, "POST", "/classes/1/promote", `{"to_class_id": 2}`), http.StatusConflict, nil)
+
+	// the students stay in 9A until the term starts
+	var student models.Student
+	expect(t, send(t, server, "GET", "/students/1", ""), http.StatusOK, &student)
+	if student.ClassID != 1 {
+		t.Errorf("got class %d before the term", student.ClassID)
+	}
+	moved, err := h.Enrollments.ApplyOn(context.Background(), today)
+	if err != nil || moved != 0 {
+		t.Errorf("moved %d students today: %v", moved, err)
+	}
+
+	moved, err = h.Enrollments.ApplyOn(context.Background(), start)
+	if err != nil || moved != 2 {
+		t.Fatalf("moved %d students on the first day of the term: %v", moved, err)
+	}
+	expect(t, send(t, server, "GET", "/students/1", ""), http.StatusOK, &student)
+	if student.ClassID != 2 || student.Class != "9B" {
+		t.Errorf("got %+v once the term started", student)
+	}
+}
@@ -1,31 +1,490 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func ExecsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		w.Write([]byte("Hello GET Method on Execs route"))
-		fmt.Println("Hello GET Method on Execs route")
+// execFilterFields are the columns execs can be filtered on, with their operators
+var execFilterFields = utils.FilterFields{
+	"id":         utils.NumberOperators,
+	"first_name": utils.TextOperators,
+	"last_name":  utils.TextOperators,
+	"email":      utils.TextOperators,
+	"username":   utils.TextOperators,
+	"role":       utils.TextOperators,
+}
+
+// execListFields are the columns execs can be sorted on
+var execListFields = []string{"first_name", "last_name", "email", "username", "role"}
+
+// execPatchableFields are the json fields an exec can change through PATCH. The password and the
+// timestamp and token columns have their own flows.
+var execPatchableFields = []string{"first_name", "last_name", "email", "username", "inactive_status", "role"}
+
+// execCreateFields are the json fields a new exec can be sent with
+var execCreateFields = append([]string{"password"}, execPatchableFields...)
+
+// checkExecPatch rejects a patch touching a field outside execPatchableFields, tests included so
+// the password hash can't be probed. A bulk patch also carries the id of each exec.
+func checkExecPatch(patch repositories.Patch, bulk bool) error {
+	allowed := func(field string) bool {
+		return utils.ContainsString(execPatchableFields, field) || (bulk && field == "id")
+	}
+
+	for field := range patch.Fields {
+		if !allowed(field) {
+			return utils.Validation(fmt.Sprintf("field %s cannot be updated", field))
+		}
+	}
+	for _, op := range patch.Ops {
+		for _, pointer := range []string{op.Path, op.From} {
+			field := strings.TrimPrefix(pointer, "/")
+			if pointer != "" && !utils.ContainsString(execPatchableFields, field) {
+				return utils.Validation(fmt.Sprintf("field %s cannot be updated", field))
+			}
+		}
+	}
+	return nil
+}
+
+// GET /execs, paginated like the other lists. Fieldsets are not offered, the listed columns are
+// all there is to read of an exec.
+func (h *Handler) GetExecsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := utils.ParseFilters(r, execFilterFields)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, execListFields),
+		Page:    page,
+		Limit:   limit,
+	}
+
+	keyset, err := keysetOptions(r, &opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	execs, err := h.Execs.List(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if keyset {
+		writeKeysetPage(w, r, opts, execs)
 		return
-	case http.MethodPost:
-		w.Write([]byte("Hello POST Method on Execs route"))
-		fmt.Println("Hello POST Method on Execs route")
+	}
+
+	total, err := h.Execs.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
-	case http.MethodPut:
-		w.Write([]byte("Hello PUT Method on Execs route"))
-		fmt.Println("Hello PUT Method on Execs route")
+	}
+	totalPages := utils.TotalPages(total, limit)
+
+	response := struct {
+		Status     string        `json:"status"`
+		Count      int           `json:"count"`
+		Total      int           `json:"total"`
+		Page       int           `json:"page"`
+		Limit      int           `json:"limit"`
+		TotalPages int           `json:"total_pages"`
+		Data       []models.Exec `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(execs),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       execs,
+	}
+
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /execs/{id}
+func (h *Handler) GetOneExecHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Exec ID"))
 		return
-	case http.MethodPatch:
-		w.Write([]byte("Hello PATCH Method on Execs route"))
-		fmt.Println("Hello PATCH Method on Execs route")
+	}
+
+	exec, err := h.Execs.Get(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "exec not found"))
 		return
-	case http.MethodDelete:
-		w.Write([]byte("Hello DELETE Method on Execs route"))
-		fmt.Println("Hello DELETE Method on Execs route")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exec)
+}
+
+// POST /execs
+func (h *Handler) AddExecsHandler(w http.ResponseWriter, r *http.Request) {
+	var newExecs []models.Exec
+	var rawExecs []map[string]interface{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
 		return
 	}
+
+	defer r.Body.Close()
+
+	err = json.Unmarshal(body, &rawExecs)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	// the id, timestamps and password hash are set by the repository
+	for _, exec := range rawExecs {
+		for key := range exec {
+			if !utils.ContainsString(execCreateFields, key) {
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
+				return
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, &newExecs)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	for i, exec := range newExecs {
+		// new execs get the least privileged role unless one is given
+		if exec.Role == "" {
+			newExecs[i].Role = models.RoleExec
+		}
+		err := CheckBlankFields(newExecs[i])
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+		err = utils.ValidationFailed(utils.AtIndex(utils.Validate(newExecs[i]), i))
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	addedExecs, err := h.Execs.Create(r.Context(), newExecs)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Exec `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedExecs),
+		Data:   addedExecs,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// PATCH FOR MULTIPLE ENTRIES /execs
+func (h *Handler) PatchExecsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
+
+	patches, err := decodeBulkPatch[models.Exec](r, "exec", false)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	for _, patch := range patches {
+		err = checkExecPatch(patch, true)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	_, err = h.Execs.Patch(r.Context(), patches)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "exec not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PATCH /execs/{id}
+func (h *Handler) PatchOneExecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Exec ID"))
+		return
+	}
+
+	patch, err := decodePatch[models.Exec](r, id, 0)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	err = checkExecPatch(patch, false)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	updatedExecs, err := h.Execs.Patch(r.Context(), []repositories.Patch{patch})
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "exec not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedExecs[0])
+}
+
+// DELETE /execs/{id}
+func (h *Handler) DeleteOneExecHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Exec ID"))
+		return
+	}
+
+	err = h.Execs.Delete(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "exec not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE MULTIPLE EXECS /execs
+func (h *Handler) DeleteExecsHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid request payload"))
+		return
+	}
+
+	deletedIds, err := h.Execs.BulkDelete(r.Context(), ids)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, ""))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status     string `json:"status"`
+		DeletedIDs []int  `json:"deleted_ids"`
+	}{
+		Status:     "Execs successfully deleted",
+		DeletedIDs: deletedIds,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /execs/login
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Exec
+	// Data Validation
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid request body"))
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Username == "" || req.Password == "" {
+		utils.WriteError(w, r, utils.Validation("Username and password are required"))
+		return
+	}
+
+	// search for user if user actually exists
+	user, err := h.Execs.GetByUsername(r.Context(), req.Username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.WriteError(w, r, utils.Unauthorized("incorrect username or password"))
+		return
+	} else if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// is user active
+	if user.InactiveStatus {
+		utils.WriteError(w, r, utils.Forbidden("Account is inactive"))
+		return
+	}
+
+	// verify password
+	err = utils.VerifyPassword(req.Password, user.Password)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// generate token
+	tokenString, expiresAt, err := utils.SignToken(user.ID, user.Username, user.Email, user.Role)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Could not create login token"))
+		return
+	}
+
+	// send token as a response or as a cookie that expires with it
+	http.SetCookie(w, &http.Cookie{
+		Name:     "Bearer",
+		Value:    tokenString,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		Expires:  expiresAt,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Token string `json:"token"`
+	}{
+		Token: tokenString,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /execs/logout
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "Bearer",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Logged out successfully"}`))
+}
+
+// POST /execs/forgotpassword
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Email == "" {
+		utils.WriteError(w, r, utils.Validation("Invalid request body"))
+		return
+	}
+	defer r.Body.Close()
+
+	duration, err := strconv.Atoi(os.Getenv("RESET_TOKEN_EXP_DURATION"))
+	if err != nil {
+		duration = 10
+	}
+	expiry := time.Now().Add(time.Duration(duration) * time.Minute)
+
+	token, hashedToken, err := utils.GenerateResetToken()
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Failed to send password reset email"))
+		return
+	}
+
+	// the same response is sent whether or not the email exists so accounts can't be enumerated
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "If an account with that email exists, a password reset link has been sent",
+	}
+
+	exec, err := h.Execs.SaveResetToken(r.Context(), req.Email, hashedToken, expiry)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Failed to send password reset email"))
+		return
+	}
+
+	if exec != nil {
+		// the link points at the configured address, never at the Host the client sent
+		resetURL := fmt.Sprintf("%s/execs/resetpassword/reset/%s", strings.TrimSuffix(h.BaseURL, "/"), token)
+		message := fmt.Sprintf("Forgot your password? Reset your password using the following link:\n%s\nIf you didn't request a password reset, please ignore this email. This link is only valid for %d minutes.", resetURL, duration)
+
+		// a failure is only logged, answering with an error would tell that the account exists
+		err = h.Mailer.Send(exec.Email, "Your password reset link", message)
+		if err != nil {
+			log.Printf("sending the password reset email of exec %d: %v", exec.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /execs/resetpassword/reset/{resetcode}
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("resetcode")
+
+	var req struct {
+		NewPassword     string `json:"new_password"`
+		ConfirmPassword string `json:"confirm_password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid values in request"))
+		return
+	}
+	defer r.Body.Close()
+
+	if req.NewPassword == "" || req.ConfirmPassword == "" {
+		utils.WriteError(w, r, utils.Validation("Password and confirm password are required"))
+		return
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		utils.WriteError(w, r, utils.Validation("Passwords should match"))
+		return
+	}
+
+	err = h.Execs.ResetPassword(r.Context(), utils.HashResetToken(token), req.NewPassword)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Password reset successfully"}`))
 }
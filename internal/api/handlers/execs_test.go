@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"restapi/internal/api/handlers"
+	"restapi/internal/api/routers"
+	"restapi/internal/models"
+	"restapi/internal/repositories/memory"
+	"restapi/pkg/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetLink is the link of the password reset email
+var resetLink = regexp.MustCompile(`(\S+)/execs/resetpassword/reset/(\S+)`)
+
+// brokenMailer fails to send anything
+type brokenMailer struct{}
+
+func (brokenMailer) Send(to, subject, body string) error {
+	return errors.New("mail server is down")
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	dir := t.TempDir()
+	execs := memory.NewExecRepo(models.Exec{ID: 1, Email: login, Username: "jsmith", Role: models.RoleAdmin})
+	server := routers.MainRouter(&handlers.Handler{Execs: execs, Mailer: utils.FileMailer{Dir: dir}, BaseURL: "https://api.school.test/"})
+
+	// an unknown email gets the same answer and no email
+	expect(t, send(t, server, "POST", "/execs/forgotpassword", `{"email": "nobody@school.test"}`), http.StatusOK, nil)
+	expect(t, send(t, server, "POST", "/execs/forgotpassword", `{"email": "`+login+`"}`, "Host", "evil.test"), http.StatusOK, nil)
+
+	sent, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(sent) != 1 {
+		t.Fatalf("got the emails %v, %v", sent, err)
+	}
+	email, err := os.ReadFile(sent[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := resetLink.FindSubmatch(email)
+	if link == nil {
+		t.Fatalf("no reset link in %s", email)
+	}
+	if string(link[1]) != "https://api.school.test" {
+		t.Errorf("the link points at %s", link[1])
+	}
+	path := "/execs/resetpassword/reset/" + string(link[2])
+
+	expect(t, send(t, server, "POST", path, `{"new_password": "n3w password", "confirm_password": "other"}`), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "POST", "/execs/resetpassword/reset/guessed", `{"new_password": "n3w password", "confirm_password": "n3w password"}`), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "POST", path, `{"new_password": "n3w password", "confirm_password": "n3w password"}`), http.StatusOK, nil)
+
+	exec, _ := execs.Exec(1)
+	if err := utils.VerifyPassword("n3w password", exec.Password); err != nil {
+		t.Errorf("the new password does not verify: %v", err)
+	}
+
+	// the token only works once
+	expect(t, send(t, server, "POST", path, `{"new_password": "again", "confirm_password": "again"}`), http.StatusBadRequest, nil)
+}
+
+func TestForgotPasswordMailFailure(t *testing.T) {
+	execs := memory.NewExecRepo(models.Exec{ID: 1, Email: login, Username: "jsmith", Role: models.RoleAdmin})
+	server := routers.MainRouter(&handlers.Handler{Execs: execs, Mailer: brokenMailer{}, BaseURL: "https://api.school.test"})
+
+	// a known and an unknown email can't be told apart by a failure to send
+	unknown := send(t, server, "POST", "/execs/forgotpassword", `{"email": "nobody@school.test"}`)
+	known := send(t, server, "POST", "/execs/forgotpassword", `{"email": "`+login+`"}`)
+	expect(t, known, http.StatusOK, nil)
+	if known.Body.String() != unknown.Body.String() {
+		t.Errorf("got %s and %s", known.Body, unknown.Body)
+	}
+}
+
+const execsJSON = `[
+	{"first_name": "Jo", "last_name": "Smith", "email": "jo@school.test", "username": "jsmith", "password": "s3cret pass", "role": "admin"},
+	{"first_name": "Al", "last_name": "Zed", "email": "al@school.test", "username": "azed", "password": "s3cret pass", "role": "manager"},
+	{"first_name": "Bo", "last_name": "Adams", "email": "bo@school.test", "username": "badams", "password": "s3cret pass"}
+]`
+
+func TestExecs(t *testing.T) {
+	server := newServer(t, false)
+
+	// the repository sets the id, the timestamps and the password hash
+	for _, field := range []string{`"id": 7`, `"user_created_at": "2024-01-01T00:00:00Z"`, `"password_changed_at": "2024-01-01T00:00:00Z"`} {
+		body := `[{"first_name": "Cy", "last_name": "Cole", "email": "cy@school.test", "username": "ccole", "password": "s3cret pass", ` + field + `}]`
+		expect(t, send(t, server, "POST", "/execs", body), http.StatusBadRequest, nil)
+	}
+
+	var added page[models.Exec]
+	expect(t, send(t, server, "POST", "/execs", execsJSON), http.StatusCreated, &added)
+	if added.Count != 3 || added.Data[2].Role != models.RoleExec || added.Data[0].UserCreatedAt == nil {
+		t.Fatalf("got %+v", added)
+	}
+	expect(t, send(t, server, "POST", "/execs", `[{"first_name": "Cy", "last_name": "Cole", "email": "cy@school.test", "username": "JSMITH", "password": "s3cret pass"}]`), http.StatusConflict, nil)
+
+	w := send(t, server, "GET", "/execs?sortby=last_name:asc&limit=2", "")
+	var list page[models.Exec]
+	expect(t, w, http.StatusOK, &list)
+	if list.Total != 3 || list.Count != 2 || list.Data[0].Username != "badams" || list.Data[1].Username != "jsmith" {
+		t.Errorf("got %+v", list)
+	}
+	if !strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("got the Link header %q", w.Header().Get("Link"))
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Errorf("the list has a password: %s", w.Body)
+	}
+
+	expect(t, send(t, server, "GET", "/execs?role=manager", ""), http.StatusOK, &list)
+	if list.Total != 1 || list.Data[0].Username != "azed" {
+		t.Errorf("got %+v", list)
+	}
+	expect(t, send(t, server, "GET", "/execs?password=x", ""), http.StatusOK, &list)
+	if list.Total != 3 {
+		t.Errorf("filtered on the password: %+v", list)
+	}
+
+	expect(t, send(t, server, "GET", "/execs?sortby=username:asc&limit=2&cursor=", ""), http.StatusOK, &list)
+	expect(t, send(t, server, "GET", "/execs?sortby=username:asc&limit=2&cursor="+list.NextCursor, ""), http.StatusOK, &list)
+	if list.Count != 1 || list.Data[0].Username != "jsmith" {
+		t.Errorf("got %+v", list)
+	}
+}
+
+func TestPatchExecs(t *testing.T) {
+	server := newServer(t, false)
+	expect(t, send(t, server, "POST", "/execs", execsJSON), http.StatusCreated, nil)
+
+	for _, body := range []string{`{"password": "n3w password"}`, `{"user_created_at": "2024-01-01T00:00:00Z"}`, `{"id": 2}`} {
+		expect(t, send(t, server, "PATCH", "/execs/1", body), http.StatusBadRequest, nil)
+	}
+	// a test would tell whether a guess of the hash is right
+	expect(t, send(t, server, "PATCH", "/execs/1", `[{"op": "test", "path": "/password", "value": ""}]`, "Content-Type", "application/json-patch+json"), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "PATCH", "/execs", `[{"id": 1, "password": "n3w password"}]`), http.StatusBadRequest, nil)
+
+	var exec models.Exec
+	expect(t, send(t, server, "PATCH", "/execs/1", `{"first_name": "Joe"}`), http.StatusOK, &exec)
+	if exec.FirstName != "Joe" || exec.Password != "" {
+		t.Errorf("got %+v", exec)
+	}
+	expect(t, send(t, server, "PATCH", "/execs/1", `[{"op": "replace", "path": "/role", "value": "manager"}]`, "Content-Type", "application/json-patch+json"), http.StatusOK, &exec)
+	if exec.Role != models.RoleManager {
+		t.Errorf("got %+v", exec)
+	}
+	expect(t, send(t, server, "PATCH", "/execs/1", `{"role": "owner"}`), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "PATCH", "/execs/1", `{"username": "azed"}`), http.StatusConflict, nil)
+
+	expect(t, send(t, server, "PATCH", "/execs", `[{"id": 2, "inactive_status": true}, {"id": 3, "last_name": "Adamson"}]`), http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/execs/3", ""), http.StatusOK, &exec)
+	if exec.LastName != "Adamson" {
+		t.Errorf("got %+v", exec)
+	}
+
+	expect(t, send(t, server, "DELETE", "/execs", `[2, 9]`), http.StatusNotFound, nil)
+	expect(t, send(t, server, "DELETE", "/execs/2", ""), http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/execs/2", ""), http.StatusNotFound, nil)
+}
+
+func TestLogin(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test secret")
+	t.Setenv("JWT_EXPIRES_IN", "2h")
+	server := newServer(t, false)
+	expect(t, send(t, server, "POST", "/execs", execsJSON), http.StatusCreated, nil)
+	expect(t, send(t, server, "PATCH", "/execs/2", `{"inactive_status": true}`), http.StatusOK, nil)
+
+	expect(t, send(t, server, "POST", "/execs/login", `{"username": "jsmith", "password": "wrong"}`), http.StatusUnauthorized, nil)
+	expect(t, send(t, server, "POST", "/execs/login", `{"username": "nobody", "password": "s3cret pass"}`), http.StatusUnauthorized, nil)
+	expect(t, send(t, server, "POST", "/execs/login", `{"username": "azed", "password": "s3cret pass"}`), http.StatusForbidden, nil)
+
+	w := send(t, server, "POST", "/execs/login", `{"username": "jsmith", "password": "s3cret pass"}`)
+	var body struct {
+		Token string `json:"token"`
+	}
+	expect(t, w, http.StatusOK, &body)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != body.Token {
+		t.Fatalf("got the cookies %v", cookies)
+	}
+	// the cookie expires with the token
+	if lifetime := time.Until(cookies[0].Expires); lifetime < 119*time.Minute || lifetime > 2*time.Hour {
+		t.Errorf("the cookie expires in %v", lifetime)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	expect(t, send(t, server, "POST", "/execs/login", `{"username": "jsmith", "password": "s3cret pass"}`), http.StatusInternalServerError, nil)
+}
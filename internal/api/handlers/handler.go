@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"os"
+	"restapi/internal/repositories"
+	"restapi/internal/repositories/sqlconnect"
+	"restapi/pkg/utils"
+	"time"
+)
+
+// Handler holds the dependencies shared by all route handlers.
+// One instance is created at startup and its methods are registered on the router.
+// Tests can build a Handler directly with the in-memory repositories instead.
+// RequireIfMatch turns on strict mode, where writes to a single record must send If-Match.
+// PurgeRetention is how long soft deleted records are kept before a purge removes them.
+// BaseURL is the public address of the API that links in emails point at.
+type Handler struct {
+	Teachers       repositories.TeacherRepository
+	Students       repositories.StudentRepository
+	Classes        repositories.ClassRepository
+	Subjects       repositories.SubjectRepository
+	AcademicYears  repositories.AcademicYearRepository
+	Terms          repositories.TermRepository
+	Enrollments    repositories.EnrollmentRepository
+	Attendance     repositories.AttendanceRepository
+	Search         repositories.SearchRepository
+	Execs          repositories.ExecRepository
+	Mailer         utils.Mailer
+	BaseURL        string
+	RequireIfMatch bool
+	PurgeRetention time.Duration
+}
+
+// NewHandler wires the MySQL repositories on top of the shared connection pool
+func NewHandler(db *sql.DB, mailer utils.Mailer) *Handler {
+	retention, err := time.ParseDuration(os.Getenv("PURGE_RETENTION"))
+	if err != nil {
+		retention = 30 * 24 * time.Hour
+	}
+
+	return &Handler{
+		Teachers:      sqlconnect.NewTeacherRepo(db),
+		Students:      sqlconnect.NewStudentRepo(db),
+		Classes:       sqlconnect.NewClassRepo(db),
+		Subjects:      sqlconnect.NewSubjectRepo(db),
+		AcademicYears: sqlconnect.NewAcademicYearRepo(db),
+		Terms:         sqlconnect.NewTermRepo(db),
+		Enrollments:   sqlconnect.NewEnrollmentRepo(db),
+		Attendance:    sqlconnect.NewAttendanceRepo(db),
+		Search:        sqlconnect.NewSearchRepo(db),
+		Execs:         sqlconnect.NewExecRepo(db),
+		Mailer:        mailer,
+
+		RequireIfMatch: os.Getenv("REQUIRE_IF_MATCH") == "true",
+		PurgeRetention: retention,
+	}
+}
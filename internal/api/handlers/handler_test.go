@@ -0,0 +1,170 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"restapi/internal/api/handlers"
+	"restapi/internal/api/routers"
+	"restapi/internal/models"
+	"restapi/internal/repositories/memory"
+	"restapi/pkg/utils"
+	"strings"
+	"testing"
+)
+
+// login is the email of the exec the requests are made by, which teacher 1 shares
+const login = "jo.smith@school.test"
+
+// newServer serves the router over newHandler. Every request is made by an admin whose email is
+// login.
+func newServer(t *testing.T, strict bool) http.Handler {
+	t.Helper()
+	return serve(newHandler(t, strict))
+}
+
+// newHandler builds a Handler on the in-memory repositories, with the classes 9A and 9B
+func newHandler(t *testing.T, strict bool) *handlers.Handler {
+	t.Helper()
+	t.Setenv("CURSOR_SECRET", "test secret")
+
+	teachers, students := memory.NewTeacherRepo(), memory.NewStudentRepo()
+	classes := memory.NewClassRepo(teachers, students)
+	years := memory.NewAcademicYearRepo()
+	terms := memory.NewTermRepo(years)
+	h := &handlers.Handler{
+		Teachers:       teachers,
+		Students:       students,
+		Classes:        classes,
+		Subjects:       memory.NewSubjectRepo(teachers),
+		AcademicYears:  years,
+		Terms:          terms,
+		Enrollments:    memory.NewEnrollmentRepo(students, terms, classes),
+		Attendance:     memory.NewAttendanceRepo(students, classes),
+		Search:         memory.NewSearchRepo(teachers, students),
+		Execs:          memory.NewExecRepo(),
+		RequireIfMatch: strict,
+	}
+
+	_, err := classes.Create(context.Background(), []models.Class{{Name: "9A", GradeLevel: 9}, {Name: "9B", GradeLevel: 9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// serve serves the router over h to an admin whose email is login
+func serve(h *handlers.Handler) http.Handler {
+	return serveAs(h, models.RoleAdmin)
+}
+
+// serveAs serves the router over h to a user with role whose email is login
+func serveAs(h *handlers.Handler, role string) http.Handler {
+	router := routers.MainRouter(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// stands in for the JWT middleware
+		ctx := context.WithValue(r.Context(), utils.ContextKey("role"), role)
+		ctx = context.WithValue(ctx, utils.ContextKey("userId"), float64(1))
+		ctx = context.WithValue(ctx, utils.ContextKey("email"), login)
+		router.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// send makes a request to server with body and the header name and value pairs of header
+func send(t *testing.T, server http.Handler, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		if header[i] == "Host" {
+			r.Host = header[i+1]
+			continue
+		}
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+// expect fails the test when the response does not have status, then decodes its body into v
+func expect(t *testing.T, w *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	err := json.Unmarshal(w.Body.Bytes(), v)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", w.Body.String(), err)
+	}
+}
+
+// page is the body of a teacher or student list
+type page[T any] struct {
+	Count      int    `json:"count"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor"`
+	Data       []T    `json:"data"`
+}
+
+const teachersJSON = `[
+	{"first_name": "Jo", "last_name": "Smith", "email": "jo.smith@school.test", "class": "9A", "subject": "Math"},
+	{"first_name": "Al", "last_name": "Zed", "email": "al.zed@school.test", "class": "9B", "subject": "Art"},
+	{"first_name": "Bo", "last_name": "Smith", "email": "bo.smith@school.test", "class": "9A", "subject": "Biology"},
+	{"first_name": "Cy", "last_name": "Adams", "email": "cy.adams@school.test", "class": "9B", "subject": "Math"},
+	{"first_name": "Di", "last_name": "Brown", "email": "di.brown@school.test", "class": "9A", "subject": "Physics"}
+]`
+
+// addTeachers adds the teachers of teachersJSON, with the ids 1 to 5
+func addTeachers(t *testing.T, server http.Handler) {
+	t.Helper()
+	expect(t, send(t, server, "POST", "/teachers", teachersJSON), http.StatusCreated, nil)
+}
+
+func TestRoles(t *testing.T) {
+	h := newHandler(t, false)
+	addTeachers(t, serve(h))
+	exec, manager := serveAs(h, models.RoleExec), serveAs(h, models.RoleManager)
+
+	expect(t, send(t, exec, "GET", "/teachers", ""), http.StatusOK, nil)
+	expect(t, send(t, exec, "GET", "/teachers/1", ""), http.StatusOK, nil)
+
+	var problem struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+	}
+	w := send(t, exec, "DELETE", "/teachers/1", "")
+	expect(t, w, http.StatusForbidden, &problem)
+	if problem.Status != http.StatusForbidden || problem.Title != "Forbidden" || problem.Instance != "/teachers/1" || !strings.Contains(problem.Detail, `"exec"`) {
+		t.Errorf("got %+v", problem)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("got Content-Type %s", contentType)
+	}
+
+	body := `[{"first_name": "Ed", "last_name": "North", "email": "ed.north@school.test", "class": "9A", "subject": "Math"}]`
+	expect(t, send(t, exec, "POST", "/teachers", body), http.StatusForbidden, nil)
+	expect(t, send(t, exec, "PATCH", "/teachers/1", `{"subject": "Art"}`), http.StatusForbidden, nil)
+	expect(t, send(t, exec, "GET", "/teachers?include_deleted=true", ""), http.StatusForbidden, nil)
+
+	// managers may change records but not delete them
+	expect(t, send(t, manager, "POST", "/teachers", body), http.StatusCreated, nil)
+	expect(t, send(t, manager, "DELETE", "/teachers/6", ""), http.StatusForbidden, nil)
+	expect(t, send(t, manager, "POST", "/teachers/purge", ""), http.StatusForbidden, nil)
+	expect(t, send(t, serve(h), "DELETE", "/teachers/6", ""), http.StatusNoContent, nil)
+
+	expect(t, send(t, serveAs(h, ""), "POST", "/teachers", body), http.StatusForbidden, &problem)
+	if problem.Detail != "no role found for the logged in user" {
+		t.Errorf("got %q without a role", problem.Detail)
+	}
+}
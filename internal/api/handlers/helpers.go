@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"reflect"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
 	"restapi/pkg/utils"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func CheckFieldNames(model interface{}) []string {
@@ -24,9 +33,447 @@ func CheckBlankFields(value interface{}) error {
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		if field.Kind() == reflect.String && field.String() == "" {
-			// http.Error(w, "All fields are required", http.StatusBadRequest)
-			return utils.ErrorHandler(errors.New("all fields are required"), "all fields are required")
+			return utils.Validation("all fields are required")
 		}
 	}
 	return nil
 }
+
+// DbFieldNames returns the db column names of the model, without the id column
+func DbFieldNames(model interface{}) []string {
+	val := reflect.TypeOf(model)
+	fields := []string{}
+
+	for i := 0; i < val.NumField(); i++ {
+		dbTag := strings.TrimSuffix(val.Field(i).Tag.Get("db"), ",omitempty")
+		if dbTag != "" && dbTag != "id" {
+			fields = append(fields, dbTag)
+		}
+	}
+	return fields
+}
+
+// ParseFieldSet reads the fields query param, e.g. fields=id,first_name,email. Every name must be
+// both a json and a db tag of model, so it can be selected and serialized under the same name.
+func ParseFieldSet(r *http.Request, model interface{}) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	allowed := make(map[string]bool)
+	dbFields := DbFieldNames(model)
+	for _, jsonField := range CheckFieldNames(model) {
+		if jsonField == "id" || utils.ContainsString(dbFields, jsonField) {
+			allowed[jsonField] = true
+		}
+	}
+
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if !allowed[field] {
+			return nil, utils.Validation(fmt.Sprintf("unknown field %q in fields", field))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// projectFields keeps only fields of value, keyed by json tag. With no fields value is returned as is.
+func projectFields(value interface{}, fields []string) interface{} {
+	if len(fields) == 0 {
+		return value
+	}
+
+	val := reflect.ValueOf(value)
+	projected := make(map[string]interface{}, len(fields))
+	for i := 0; i < val.NumField(); i++ {
+		name := strings.TrimSuffix(val.Type().Field(i).Tag.Get("json"), ",omitempty")
+		if utils.ContainsString(fields, name) {
+			projected[name] = val.Field(i).Interface()
+		}
+	}
+	return projected
+}
+
+// projectList applies projectFields to every item of list
+func projectList[T any](list []T, fields []string) interface{} {
+	if len(fields) == 0 {
+		return list
+	}
+
+	projected := make([]interface{}, len(list))
+	for i, item := range list {
+		projected[i] = projectFields(item, fields)
+	}
+	return projected
+}
+
+// patchID reads the id of an entry in a bulk PATCH body, sent either as a string or a number
+func patchID(value interface{}) (int, error) {
+	switch id := value.(type) {
+	case string:
+		return strconv.Atoi(id)
+	case float64:
+		if id != float64(int(id)) {
+			return 0, fmt.Errorf("invalid id %v", id)
+		}
+		return int(id), nil
+	default:
+		return 0, fmt.Errorf("invalid id %v", value)
+	}
+}
+
+// patchVersion reads the optional version of an entry in a bulk PATCH body, 0 when it is not sent
+func patchVersion(value interface{}) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+	return patchID(value)
+}
+
+// decodeBulkDelete reads the body of a bulk DELETE, a list of ids or of objects with the id and,
+// optionally, the version of the record. With requireVersion every entry needs its version.
+func decodeBulkDelete(r *http.Request, resource string, requireVersion bool) ([]repositories.Deletion, error) {
+	var entries []interface{}
+	err := json.NewDecoder(r.Body).Decode(&entries)
+	if err != nil {
+		return nil, utils.Validation("Invalid request payload")
+	}
+
+	deletions := make([]repositories.Deletion, 0, len(entries))
+	for _, entry := range entries {
+		var deletion repositories.Deletion
+		object, ok := entry.(map[string]interface{})
+		if !ok {
+			object = map[string]interface{}{"id": entry}
+		}
+		deletion.ID, err = patchID(object["id"])
+		if err != nil {
+			return nil, utils.Validation(fmt.Sprintf("Invalid %s ID in request", resource))
+		}
+		deletion.Version, err = patchVersion(object["version"])
+		if err != nil {
+			return nil, utils.Validation(fmt.Sprintf("Invalid %s version in request", resource))
+		}
+		if deletion.Version == 0 && requireVersion {
+			return nil, utils.PreconditionRequired("every id needs the version it expects")
+		}
+		deletions = append(deletions, deletion)
+	}
+	return deletions, nil
+}
+
+// ifMatchVersion returns the version the If-Match header requires the record to be at, or 0
+// for any version. A missing header is only an error in strict mode. When the header lists
+// several versions the record's version is read with current, and required if it is one of
+// them, so the repository still fails the write if the record changes in between.
+func (h *Handler) ifMatchVersion(r *http.Request, current func() (int, error)) (int, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if h.RequireIfMatch {
+			return 0, utils.PreconditionRequired("If-Match header is required")
+		}
+		return 0, nil
+	}
+
+	versions, err := utils.ParseIfMatch(header)
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+
+	version, err := current()
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, version) {
+		return 0, utils.PreconditionFailed("If-Match " + header + " does not match the current ETag")
+	}
+	return version, nil
+}
+
+// writeConditional sends body as JSON with an ETag, and with a Last-Modified when lastModified is
+// set. An empty etag is computed from the encoded body. A GET whose If-None-Match or
+// If-Modified-Since still matches gets 304 Not Modified instead of the body.
+func writeConditional(w http.ResponseWriter, r *http.Request, etag string, lastModified *time.Time, body interface{}) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "error encoding response"))
+		return
+	}
+
+	if etag == "" {
+		etag = utils.ContentETag(encoded)
+	}
+	w.Header().Set("ETag", etag)
+
+	var modified time.Time
+	if lastModified != nil {
+		modified = *lastModified
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if utils.NotModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(encoded, '\n'))
+}
+
+// validatePatch checks the fields of one patch against the validate tags of model, which must
+// point to an empty struct. Fields that are not sent are not checked.
+func validatePatch(model interface{}, fields map[string]interface{}) ([]utils.FieldError, error) {
+	err := repositories.ApplyPatch(model, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return utils.ValidateFields(model, names), nil
+}
+
+// repoError replaces a repository ErrNotFound with notFoundMessage, when one is given.
+// Every other error is already typed by the repository and is returned as is.
+func repoError(err error, notFoundMessage string) error {
+	if notFoundMessage != "" && errors.Is(err, repositories.ErrNotFound) {
+		return utils.NotFound(notFoundMessage)
+	}
+	return err
+}
+
+// keysetOptions switches opts to cursor pagination when the request has a cursor parameter.
+// An empty cursor starts at the first row.
+func keysetOptions(r *http.Request, opts *repositories.ListOptions) (bool, error) {
+	if !r.URL.Query().Has("cursor") {
+		return false, nil
+	}
+
+	encoded := r.URL.Query().Get("cursor")
+	if encoded == "" {
+		return true, nil
+	}
+
+	after, err := utils.DecodeCursor(encoded, opts.Sort)
+	if err != nil {
+		return true, err
+	}
+	opts.After = after
+	return true, nil
+}
+
+// writeKeysetPage sends a cursor paginated list. A full page gets a next_cursor pointing after its last row.
+func writeKeysetPage[T any](w http.ResponseWriter, r *http.Request, opts repositories.ListOptions, list []T) {
+	nextCursor := ""
+	if opts.Limit > 0 && len(list) == opts.Limit {
+		var err error
+		nextCursor, err = utils.EncodeCursor(utils.NewCursor(opts.Sort, list[len(list)-1]))
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	response := struct {
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Limit      int         `json:"limit"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+		Data       interface{} `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(list),
+		Limit:      opts.Limit,
+		NextCursor: nextCursor,
+		Data:       projectList(list, opts.Fields),
+	}
+
+	if link := utils.CursorLinkHeader(r, nextCursor); link != "" {
+		w.Header().Set("Link", link)
+	}
+	writeConditional(w, r, "", nil, response)
+}
+
+// classFilter narrows a teacher or student list down to one class, which is how the two are related
+func classFilter(classID int) utils.Filter {
+	return utils.Filter{Field: "class_id", Operator: utils.OpEq, Value: strconv.Itoa(classID)}
+}
+
+// classOf returns the class_id and class fields of a teacher or student
+func classOf(model interface{}) (*int, *string) {
+	switch member := model.(type) {
+	case *models.Teacher:
+		return &member.ClassID, &member.Class
+	case *models.Student:
+		return &member.ClassID, &member.Class
+	}
+	return nil, nil
+}
+
+// resolveClass fills in the class of a teacher or student. class_id wins when it is sent and
+// the class becomes its name; otherwise class_id is looked up from the class name. Both must
+// name the same class when both are sent.
+func (h *Handler) resolveClass(ctx context.Context, model interface{}) ([]utils.FieldError, error) {
+	classID, name := classOf(model)
+	if *classID == 0 && *name == "" {
+		return nil, nil
+	}
+
+	var class models.Class
+	var err error
+	field := "class_id"
+	if *classID != 0 {
+		class, err = h.Classes.Get(ctx, *classID)
+	} else {
+		field = "class"
+		class, err = h.Classes.GetByName(ctx, *name)
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		return []utils.FieldError{{Field: field, Rule: "exists", Message: "is not a known class"}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if *name != "" && !strings.EqualFold(*name, class.Name) {
+		return []utils.FieldError{{Field: "class", Rule: "match", Message: "does not match class_id"}}, nil
+	}
+	*classID, *name = class.ID, class.Name
+	return nil, nil
+}
+
+// validateMember resolves the class of a new or replaced teacher or student, then checks its
+// validate tags. A class that is not known is not reported a second time as a missing class.
+func (h *Handler) validateMember(ctx context.Context, model interface{}) ([]utils.FieldError, error) {
+	fieldErrors, err := h.resolveClass(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	classReported := len(fieldErrors) > 0
+	for _, fieldError := range utils.Validate(model) {
+		if !classReported || fieldError.Field != "class" {
+			fieldErrors = append(fieldErrors, fieldError)
+		}
+	}
+	return fieldErrors, nil
+}
+
+// prepareMemberPatch is the Prepare of a teacher or student patch. A patch of class_id or class
+// resolves the other one; clearing class_id leaves the record without a class, which is refused.
+func (h *Handler) prepareMemberPatch(ctx context.Context) func(model interface{}, changed []string) error {
+	return func(model interface{}, changed []string) error {
+		byID, byName := utils.ContainsString(changed, "class_id"), utils.ContainsString(changed, "class")
+		if !byID && !byName {
+			return nil
+		}
+
+		classID, name := classOf(model)
+		if !byName {
+			*name = ""
+		}
+		if !byID {
+			*classID = 0
+		}
+		fieldErrors, err := h.resolveClass(ctx, model)
+		if err != nil {
+			return err
+		}
+		if len(fieldErrors) == 0 && *classID == 0 {
+			fieldErrors = []utils.FieldError{{Field: "class_id", Rule: "required", Message: "is required"}}
+		}
+		return utils.ValidationFailed(fieldErrors)
+	}
+}
+
+// includeDeleted reads ?include_deleted=true, which only admins may send
+func includeDeleted(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("include_deleted") {
+	case "", "false":
+		return false, nil
+	case "true":
+	default:
+		return false, utils.Validation("include_deleted must be true or false")
+	}
+
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	if role != models.RoleAdmin {
+		return false, utils.Forbidden("only admins can include deleted records")
+	}
+	return true, nil
+}
+
+// partialMode reports whether a bulk request asked for ?mode=partial
+func partialMode(r *http.Request) (bool, error) {
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+		return false, nil
+	case "partial":
+		return true, nil
+	default:
+		return false, utils.Validation(fmt.Sprintf("unknown mode %q, only partial is supported", mode))
+	}
+}
+
+// createPartial validates and creates each item on its own and answers 207 with one result per item,
+// in request order. Only the items that fail are left out. validate is given a pointer to each item.
+func createPartial[T any](w http.ResponseWriter, r *http.Request, items []T, validate func(context.Context, interface{}) ([]utils.FieldError, error), create func(context.Context, []T) []repositories.ItemResult[T]) {
+	type itemResult struct {
+		Index  int                    `json:"index"`
+		Status int                    `json:"status"`
+		Data   interface{}            `json:"data,omitempty"`
+		Error  map[string]interface{} `json:"error,omitempty"`
+	}
+
+	results := make([]itemResult, len(items))
+	var valid []T
+	var validIndexes []int
+	for i, item := range items {
+		fieldErrors, err := validate(r.Context(), &item)
+		if err == nil {
+			err = utils.ValidationFailed(fieldErrors)
+		}
+		if err != nil {
+			status, problem := utils.Problem(r, err)
+			results[i] = itemResult{Index: i, Status: status, Error: problem}
+			continue
+		}
+		valid = append(valid, item)
+		validIndexes = append(validIndexes, i)
+	}
+
+	created := 0
+	for j, result := range create(r.Context(), valid) {
+		i := validIndexes[j]
+		if result.Err != nil {
+			status, problem := utils.Problem(r, result.Err)
+			results[i] = itemResult{Index: i, Status: status, Error: problem}
+			continue
+		}
+		results[i] = itemResult{Index: i, Status: http.StatusCreated, Data: result.Item}
+		created++
+	}
+
+	response := struct {
+		Status  string       `json:"status"`
+		Created int          `json:"created"`
+		Failed  int          `json:"failed"`
+		Results []itemResult `json:"results"`
+	}{
+		Status:  "success",
+		Created: created,
+		Failed:  len(items) - created,
+		Results: results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"sort"
+	"strconv"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// acceptPatch is sent as Accept-Patch on PATCH routes
+var acceptPatch = "application/json, " + mergePatchType + ", " + jsonPatchType
+
+// patchFormat returns the media type of a PATCH body. Plain JSON is read as a merge patch.
+func patchFormat(r *http.Request) (string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return mergePatchType, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case jsonPatchType:
+			return jsonPatchType, nil
+		case mergePatchType, "application/json":
+			return mergePatchType, nil
+		}
+	}
+	return "", utils.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported patch format %q", contentType))
+}
+
+// decodePatch reads the body of a PATCH to the record with id as a JSON Patch, or as a merge
+// patch whose fields are checked against the validate tags of T. JSON Patch ops are checked by
+// the repository, against the record they apply to.
+func decodePatch[T any](r *http.Request, id, version int) (repositories.Patch, error) {
+	patch := repositories.Patch{ID: id, Version: version}
+
+	format, err := patchFormat(r)
+	if err != nil {
+		return patch, err
+	}
+
+	if format == jsonPatchType {
+		err = json.NewDecoder(r.Body).Decode(&patch.Ops)
+		if err != nil || patch.Ops == nil {
+			return patch, utils.Validation("Invalid Request Payload")
+		}
+		return patch, nil
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&patch.Fields)
+	if err != nil || patch.Fields == nil {
+		return patch, utils.Validation("Invalid Request Payload")
+	}
+	invalid, err := validatePatch(new(T), patch.Fields)
+	if err != nil {
+		return patch, err
+	}
+	return patch, utils.ValidationFailed(invalid)
+}
+
+// decodeBulkPatch reads the body of a bulk PATCH. A merge patch is an array of objects that each
+// carry the id, and optionally the version, of the record they change. A JSON Patch is an object
+// of documents keyed by id, where a test of /version names the version the record must be at.
+// With requireVersion every update must name its version.
+func decodeBulkPatch[T any](r *http.Request, resource string, requireVersion bool) ([]repositories.Patch, error) {
+	format, err := patchFormat(r)
+	if err != nil {
+		return nil, err
+	}
+	missingVersion := utils.PreconditionRequired("every update needs the version it expects")
+
+	if format == jsonPatchType {
+		var documents map[string][]repositories.PatchOp
+		err := json.NewDecoder(r.Body).Decode(&documents)
+		if err != nil {
+			return nil, utils.Validation("Invalid request payload")
+		}
+
+		patches := make([]repositories.Patch, 0, len(documents))
+		for key, ops := range documents {
+			id, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, utils.Validation(fmt.Sprintf("Invalid %s ID in update", resource))
+			}
+			version := testedVersion(ops)
+			if version == 0 && requireVersion {
+				return nil, missingVersion
+			}
+			patches = append(patches, repositories.Patch{ID: id, Version: version, Ops: ops})
+		}
+		// apply in id order so the outcome does not depend on map order
+		sort.Slice(patches, func(i, j int) bool { return patches[i].ID < patches[j].ID })
+		return patches, nil
+	}
+
+	var updates []map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&updates)
+	if err != nil {
+		return nil, utils.Validation("Invalid request payload")
+	}
+
+	patches := make([]repositories.Patch, 0, len(updates))
+	var fieldErrors []utils.FieldError
+	for i, update := range updates {
+		id, err := patchID(update["id"])
+		if err != nil {
+			return nil, utils.Validation(fmt.Sprintf("Invalid %s ID in update", resource))
+		}
+
+		version, err := patchVersion(update["version"])
+		if err != nil {
+			return nil, utils.Validation(fmt.Sprintf("Invalid %s version in update", resource))
+		}
+		if version == 0 && requireVersion {
+			return nil, missingVersion
+		}
+
+		invalid, err := validatePatch(new(T), update)
+		if err != nil {
+			return nil, err
+		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
+		patches = append(patches, repositories.Patch{ID: id, Version: version, Fields: update})
+	}
+	return patches, utils.ValidationFailed(fieldErrors)
+}
+
+// testedVersion returns the version a JSON Patch tests for, or 0 when it does not test /version
+func testedVersion(ops []repositories.PatchOp) int {
+	for _, op := range ops {
+		if op.Op == repositories.OpTest && op.Path == "/version" {
+			version, err := patchVersion(op.Value)
+			if err == nil {
+				return version
+			}
+		}
+	}
+	return 0
+}
@@ -1,9 +1,58 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"restapi/pkg/utils"
 )
 
 func RootHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Welcome to our School API"))
 }
+
+// Unmatched serves mux and answers the requests none of its routes match with a problem+json
+// body: 405 with the Allow header of the mux when the path has routes for other methods, 404
+// otherwise. Redirects of the mux, like the one adding a trailing slash, are served as they are.
+func Unmatched(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// the handler of the mux for a miss picks the status and Allow, its plain text body is dropped
+		miss := &missWriter{header: http.Header{}}
+		handler.ServeHTTP(miss, r)
+		switch miss.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", miss.header.Get("Allow"))
+			utils.WriteError(w, r, utils.MethodNotAllowed(fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)))
+		case http.StatusNotFound:
+			utils.WriteError(w, r, utils.NotFound(fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path)))
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// missWriter records the status and headers of a response and discards its body
+type missWriter struct {
+	header http.Header
+	status int
+}
+
+func (m *missWriter) Header() http.Header { return m.header }
+
+func (m *missWriter) Write(b []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (m *missWriter) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// GET /search?q=
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		utils.WriteError(w, r, utils.Validation("Search query q is required"))
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	results, total, err := h.Search.Search(r.Context(), query, page, limit)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
+	response := struct {
+		Status     string                `json:"status"`
+		Count      int                   `json:"count"`
+		Total      int                   `json:"total"`
+		Page       int                   `json:"page"`
+		Limit      int                   `json:"limit"`
+		TotalPages int                   `json:"total_pages"`
+		Data       []models.SearchResult `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(results),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       results,
+	}
+
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
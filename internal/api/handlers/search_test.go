@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"restapi/internal/models"
+	"strings"
+	"testing"
+)
+
+// searchPage is the body of GET /search
+type searchPage struct {
+	Count      int                   `json:"count"`
+	Total      int                   `json:"total"`
+	Page       int                   `json:"page"`
+	TotalPages int                   `json:"total_pages"`
+	Data       []models.SearchResult `json:"data"`
+}
+
+func TestSearch(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Smith", "email": "ann.smith@school.test", "class": "9B"}]`), http.StatusCreated, nil)
+
+	// the Smiths in 9A match both terms, then ties go students first and by id
+	var results searchPage
+	expect(t, send(t, server, "GET", "/search?q=Smith%209a", ""), http.StatusOK, &results)
+	var got []string
+	for _, result := range results.Data {
+		got = append(got, fmt.Sprintf("%s %d", result.Type, result.ID))
+	}
+	want := []string{"teacher 1", "teacher 3", "student 1", "teacher 5"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") || results.Total != 4 {
+		t.Errorf("got %v of %d, want %v", got, results.Total, want)
+	}
+	if results.Data[0].Score != 2 || results.Data[2].Score != 1 {
+		t.Errorf("got scores %v and %v", results.Data[0].Score, results.Data[2].Score)
+	}
+
+	// the student Smith comes first among the equal scores, so the second page holds teacher 1
+	w := send(t, server, "GET", "/search?q=smith&limit=1&page=2", "")
+	expect(t, w, http.StatusOK, &results)
+	if results.Count != 1 || results.Total != 3 || results.TotalPages != 3 || results.Data[0].Type != models.SearchTypeTeacher || results.Data[0].ID != 1 {
+		t.Errorf("got %+v", results)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) {
+		t.Errorf("got Link %q", link)
+	}
+
+	expect(t, send(t, server, "GET", "/search?q=nobody", ""), http.StatusOK, &results)
+	if results.Total != 0 || len(results.Data) != 0 {
+		t.Errorf("got %+v for no match", results)
+	}
+
+	expect(t, send(t, server, "GET", "/search?q=%20", ""), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "GET", "/search", ""), http.StatusBadRequest, nil)
+}
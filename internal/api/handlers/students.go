@@ -1,123 +1,151 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"reflect"
 	"restapi/internal/models"
-	"restapi/internal/repositories/sqlconnect"
+	"restapi/internal/repositories"
 	"restapi/pkg/utils"
 	"strconv"
+	"time"
 )
 
-func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
+// studentFilterFields declares the columns students can be filtered on and the operators each one allows
+var studentFilterFields = utils.FilterFields{
+	"id":         utils.NumberOperators,
+	"first_name": utils.TextOperators,
+	"last_name":  utils.TextOperators,
+	"email":      utils.TextOperators,
+	"class":      utils.TextOperators,
+	"class_id":   utils.NumberOperators,
+}
+
+func (h *Handler) GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	h.listStudents(w, r)
+}
+
+// listStudents answers a GET of a student list, narrowed down to the students matching scope
+func (h *Handler) listStudents(w http.ResponseWriter, r *http.Request, scope ...utils.Filter) {
+	filters, err := utils.ParseFilters(r, studentFilterFields)
 	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer db.Close()
+	filters = append(filters, scope...)
 
-	query := "SELECT id, first_name, last_name, email, class, subject FROM students WHERE 1=1"
-	var args []interface{}
+	fields, err := ParseFieldSet(r, models.Student{})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-	query, args = utils.AddFilters(r, query, args)
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-	query = utils.AddSorting(r, query)
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, DbFieldNames(models.Student{})),
+		Page:    page,
+		Limit:   limit,
+		Fields:  fields,
 
-	rows, err := db.Query(query, args...)
+		IncludeDeleted: withDeleted,
+	}
+
+	keyset, err := keysetOptions(r, &opts)
 	if err != nil {
-		fmt.Println(err)
-		// http.Error(w, "Database query error", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer rows.Close()
 
-	studentList := make([]models.Student, 0)
-	for rows.Next() {
-		var student models.Student
-		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
-		if err != nil {
-			// http.Error(w, "Error scanning database results", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error retrieving data")
-			return
-		}
-		studentList = append(studentList, student)
+	studentList, err := h.Students.List(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
 	}
 
+	// cursor pages skip the COUNT, which is what makes them cheap on large tables
+	if keyset {
+		writeKeysetPage(w, r, opts, studentList)
+		return
+	}
+
+	total, err := h.Students.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
 	response := struct {
-		Status string           `json:"status"`
-		Count  int              `json:"count"`
-		Data   []models.Student `json:"data"`
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		Limit      int         `json:"limit"`
+		TotalPages int         `json:"total_pages"`
+		Data       interface{} `json:"data"`
 	}{
-		Status: "success",
-		Count:  len(studentList),
-		Data:   studentList,
+		Status:     "success",
+		Count:      len(studentList),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       projectList(studentList, fields),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// a list has no single Last-Modified, a deleted row would not move it
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	writeConditional(w, r, "", nil, response)
 
 }
 
-func GetOneStudentHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
-	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
-		return
-	}
-	defer db.Close()
-
+func (h *Handler) GetOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 
 	// Handle path parameter
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		fmt.Println(err)
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	var student models.Student
-
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM students WHERE id = ?", id).Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
-
-	if err == sql.ErrNoRows {
-		// http.Error(w, "Student not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Student not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Database query error", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+	fields, err := ParseFieldSet(r, models.Student{})
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(student)
-}
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-// function for POST Student request handler
-func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
+	student, err := h.Students.Get(r.Context(), id, withDeleted, fields...)
 	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error adding data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
-	defer db.Close()
 
+	writeConditional(w, r, utils.ETag(student.Version), student.UpdatedAt, projectFields(student, fields))
+}
+
+// function for POST Student request handler
+func (h *Handler) AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 	var newStudents []models.Student
 	var rawStudents []map[string]interface{}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Error reading Request body", http.StatusInternalServerError)
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
 		return
 	}
 
@@ -125,8 +153,7 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &rawStudents)
 	if err != nil {
-		http.Error(w, "invalid Request body", http.StatusBadRequest)
-		utils.ErrorHandler(err, "invalid Request body")
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
 		return
 	}
 
@@ -141,7 +168,7 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		for key := range student {
 			_, ok := allowedFields[key]
 			if !ok {
-				http.Error(w, "Unacceptable fields found in request. Only use allowed fields..", http.StatusBadRequest)
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
 				return
 			}
 
@@ -150,47 +177,40 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &newStudents)
 	if err != nil {
-		http.Error(w, "invalid Request body", http.StatusBadRequest)
-		utils.ErrorHandler(err, "invalid Request body")
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	partial, err := partialMode(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if partial {
+		createPartial(w, r, newStudents, h.validateMember, h.Students.CreatePartial)
 		return
 	}
 
-	for _, student := range newStudents {
-		err := CheckBlankFields(student)
+	// report every invalid field of every item at once
+	var fieldErrors []utils.FieldError
+	for i := range newStudents {
+		invalid, err := h.validateMember(r.Context(), &newStudents[i])
 		if err != nil {
-			// http.Error(w, "invalid Request body", http.StatusBadRequest)
-			utils.ErrorHandler(err, "invalid Request body")
+			utils.WriteError(w, r, err)
 			return
 		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
 	}
-
-	// stmt, err := db.Prepare("INSERT INTO students (first_name, last_name, email, class, subject) VALUES (?,?,?,?,?)")
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("students", models.Student{}))
+	err = utils.ValidationFailed(fieldErrors)
 	if err != nil {
-		// http.Error(w, "Error preparing SQL query", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error adding data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer stmt.Close()
 
-	addedStudents := make([]models.Student, len(newStudents))
-	for i, newStudent := range newStudents {
-		// res, err := stmt.Exec(newStudent.FirstName, newStudent.LastName, newStudent.Email, newStudent.Class, newStudent.Subject)
-		values := utils.GetStructValues(newStudent)
-		res, err := stmt.Exec(values...)
-		if err != nil {
-			// http.Error(w, "Error inserting data into DB", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error adding data")
-			return
-		}
-		lastID, err := res.LastInsertId()
-		if err != nil {
-			// http.Error(w, "Error getting last insert ID", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error adding data")
-			return
-		}
-		newStudent.ID = int(lastID)
-		addedStudents[i] = newStudent
+	addedStudents, err := h.Students.Create(r.Context(), newStudents)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -209,353 +229,226 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // PUT /students/{id}
-func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid student ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid student ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	var updatedStudent models.Student
-	err = json.NewDecoder(r.Body).Decode(&updatedStudent)
+	version, err := h.ifMatchVersion(r, h.studentVersion(r.Context(), id))
 	if err != nil {
-		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	var updatedStudent models.Student
+	err = json.NewDecoder(r.Body).Decode(&updatedStudent)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
 		return
 	}
-	defer db.Close()
 
-	var existingStudent models.Student
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Student not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Unable to retrieve data", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+	invalid, err := h.validateMember(r.Context(), &updatedStudent)
+	if err == nil {
+		err = utils.ValidationFailed(invalid)
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	updatedStudent.ID = existingStudent.ID
-	_, err = db.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", updatedStudent.FirstName, updatedStudent.LastName, updatedStudent.Email, updatedStudent.Class, updatedStudent.ID)
+	updatedStudent.ID = id
+	updatedStudent.Version = version
+	updatedStudent, err = h.Students.Update(r.Context(), updatedStudent)
 	if err != nil {
-		// http.Error(w, "Unable to update student", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(updatedStudent.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedStudent)
 
 }
 
 // PATCH FOR MULTIPLE ENTRIES /students
-func PatchStudentsHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
-	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
-		return
-	}
-	defer db.Close()
-
-	var updates []map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
-	if err != nil {
-		// http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid request payload")
-		return
-	}
+func (h *Handler) PatchStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
 
-	// start transaction
-	tx, err := db.Begin()
+	patches, err := decodeBulkPatch[models.Student](r, "student", h.RequireIfMatch)
 	if err != nil {
-		// http.Error(w, "Error starting transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, err)
 		return
 	}
-
-	for _, update := range updates {
-		idStr, ok := update["id"].(string)
-		if !ok {
-			tx.Rollback()
-			// http.Error(w, "Invalid student ID in update", http.StatusBadRequest)
-			utils.ErrorHandler(err, "Invalid student ID")
-			return
-		}
-
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error converting ID to int", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
-
-		var studentFromDb models.Student
-		err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM students WHERE id = ?", id).Scan(&studentFromDb.ID, &studentFromDb.FirstName, &studentFromDb.LastName, &studentFromDb.Email, &studentFromDb.Class)
-		if err != nil {
-			tx.Rollback()
-			if err == sql.ErrNoRows {
-				http.Error(w, "Student not found", http.StatusNotFound)
-				utils.ErrorHandler(err, "Student not found")
-			}
-			// http.Error(w, "Error retrieving Student", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
-
-		// apply updates using reflect
-		studentVal := reflect.ValueOf(&studentFromDb).Elem()
-		studentType := studentVal.Type()
-
-		for k, v := range update {
-			if k == "id" {
-				continue // skip updating the id field
-			}
-			for i := 0; i < studentVal.NumField(); i++ {
-				field := studentType.Field(i)
-				if field.Tag.Get("json") == k+",omitempty" {
-					fieldVal := studentVal.Field(i)
-					if fieldVal.CanSet() {
-						val := reflect.ValueOf(v)
-						if val.Type().ConvertibleTo(fieldVal.Type()) {
-							fieldVal.Set(val.Convert(fieldVal.Type()))
-						} else {
-							tx.Rollback()
-							log.Printf("Cannot convert %v to %v", val.Type(), fieldVal.Type())
-							return
-						}
-					}
-					break
-				}
-			}
-		}
-
-		_, err = tx.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", studentFromDb.FirstName, studentFromDb.LastName, studentFromDb.Email, studentFromDb.Class, studentFromDb.ID)
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error updating student", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
+	for i := range patches {
+		patches[i].Prepare = h.prepareMemberPatch(r.Context())
 	}
 
-	// commit the transaction
-	err = tx.Commit()
+	_, err = h.Students.Patch(r.Context(), patches)
 	if err != nil {
-		// http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // PATCH /students/{id}
-func PatchOneStudentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PatchOneStudentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
+
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		// http.Error(w, "Invalid Student ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Student ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	version, err := h.ifMatchVersion(r, h.studentVersion(r.Context(), id))
 	if err != nil {
-		// http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Request payload")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	patch, err := decodePatch[models.Student](r, id, version)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer db.Close()
+	patch.Prepare = h.prepareMemberPatch(r.Context())
 
-	var existingStudent models.Student
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM students WHERE id = ?", id).Scan(&existingStudent.ID, &existingStudent.FirstName, &existingStudent.LastName, &existingStudent.Email, &existingStudent.Class)
-	if err == sql.ErrNoRows {
-		// http.Error(w, "Student not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Student not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Unable to retrieve data", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
-		return
-	}
-
-	// apply updates using reflect
-	studentVal := reflect.ValueOf(&existingStudent).Elem()
-	studentType := studentVal.Type()
-
-	for k, v := range updates {
-		for i := 0; i < studentVal.NumField(); i++ {
-			field := studentType.Field(i)
-			field.Tag.Get("json")
-			if field.Tag.Get("json") == k+",omitempty" {
-				if studentVal.Field(i).CanSet() {
-					studentVal.Field(i).Set(reflect.ValueOf(v).Convert(studentVal.Field(i).Type()))
-				}
-			}
-		}
-	}
-
-	_, err = db.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingStudent.FirstName, existingStudent.LastName, existingStudent.Email, existingStudent.Class, existingStudent.ID)
+	updatedStudents, err := h.Students.Patch(r.Context(), []repositories.Patch{patch})
 	if err != nil {
-		// http.Error(w, "Unable to update student", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(updatedStudents[0].Version))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(existingStudent)
+	json.NewEncoder(w).Encode(updatedStudents[0])
 
 }
 
 // DELETE /students/{id}
-func DeleteOneStudentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteOneStudentHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		// http.Error(w, "Invalid Student ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Student ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	version, err := h.ifMatchVersion(r, h.studentVersion(r.Context(), id))
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer db.Close()
 
-	res, err := db.Exec("DELETE FROM students WHERE id = ?", id)
+	err = h.Students.Delete(r.Context(), id, version)
 	if err != nil {
-		// http.Error(w, "Error deleting student", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	w.WriteHeader(http.StatusNoContent)
+
+}
+
+// DELETE MULTIPLE STUDENTS /students
+func (h *Handler) DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	deletions, err := decodeBulkDelete(r, "student", h.RequireIfMatch)
 	if err != nil {
-		// http.Error(w, "Error retrieving delete result", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	if rowsAffected == 0 {
-		// http.Error(w, "Student not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Student not found")
+	if len(deletions) < 1 {
+		utils.WriteError(w, r, utils.Validation("IDs do not exist"))
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-
-}
-
-// DELETE MULTIPLE STUDENTS /studentrs
-func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
+	deletedIds, err := h.Students.BulkDelete(r.Context(), deletions)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, repoError(err, ""))
 		return
 	}
-	defer db.Close()
 
-	var ids []int
-	err = json.NewDecoder(r.Body).Decode(&ids)
-	if err != nil {
-		// http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid request payload")
-		return
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status     string `json:"status"`
+		DeletedIDs []int  `json:"deleted_ids"`
+	}{
+		Status:     "Students successfully deleted",
+		DeletedIDs: deletedIds,
 	}
 
-	tx, err := db.Begin()
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /students/{id}/restore
+func (h *Handler) RestoreStudentHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		// http.Error(w, "Error starting transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM students WHERE id = ?")
+	restoredStudent, err := h.Students.Restore(r.Context(), id)
 	if err != nil {
-		tx.Rollback()
-		// http.Error(w, "Error preparing delete statement", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
-	defer stmt.Close()
-
-	deletedIds := []int{}
-	for _, id := range ids {
-		res, err := stmt.Exec(id)
-		if err != nil {
-			tx.Rollback()
 
-			// http.Error(w, "Error Deleting Student", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error deleting data")
-			return
-		}
-
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error retrieving deleted result", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error deleting data")
-			return
-		}
+	w.Header().Set("ETag", utils.ETag(restoredStudent.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restoredStudent)
+}
 
-		// if student was deleted then add the deleted id to deletedIDs slice
-		if rowsAffected > 0 {
-			deletedIds = append(deletedIds, id)
-		}
+// POST /students/purge permanently removes the students deleted longer than the retention period ago
+func (h *Handler) PurgeStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	deletedBefore := time.Now().UTC().Add(-h.PurgeRetention).Truncate(time.Second)
+	purged, err := h.Students.Purge(r.Context(), deletedBefore)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-		if rowsAffected < 1 {
-			tx.Rollback()
-			// http.Error(w, fmt.Sprintf("ID %v does not exist", id), http.StatusInternalServerError)
-			utils.ErrorHandler(err, fmt.Sprintf("ID %v does not exist", id))
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status        string    `json:"status"`
+		Purged        int       `json:"purged"`
+		DeletedBefore time.Time `json:"deleted_before"`
+	}{
+		Status:        "success",
+		Purged:        purged,
+		DeletedBefore: deletedBefore,
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	// commit
-	err = tx.Commit()
+// GET /students/{id}/teachers lists the teachers of the student's class
+func (h *Handler) GetStudentTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		// http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, utils.Validation("Invalid Student ID"))
 		return
 	}
 
-	if len(deletedIds) < 1 {
-		// http.Error(w, "IDs do not exists", http.StatusBadRequest)
-		utils.ErrorHandler(err, "IDs do not exist")
+	student, err := h.Students.Get(r.Context(), id, false)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Student not found"))
 		return
 	}
+	h.listTeachers(w, r, classFilter(student.ClassID))
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	response := struct {
-		Status     string `json:"status"`
-		DeletedIDs []int  `json:"deleted_ids"`
-	}{
-		Status:     "Students successfully deleted",
-		DeletedIDs: deletedIds,
+// studentVersion reads the version of the live student with id, for ifMatchVersion
+func (h *Handler) studentVersion(ctx context.Context, id int) func() (int, error) {
+	return func() (int, error) {
+		student, err := h.Students.Get(ctx, id, false)
+		return student.Version, repoError(err, "Student not found")
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
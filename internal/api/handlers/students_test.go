@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"restapi/internal/models"
+	"testing"
+)
+
+// addTerm adds an academic year with a term from 30 days ago to 30 days from now, with the id 1
+func addTerm(t *testing.T, server http.Handler) models.Term {
+	t.Helper()
+	today := models.Today()
+	start, end := models.Date{Time: today.AddDate(0, 0, -30)}, models.Date{Time: today.AddDate(0, 0, 30)}
+
+	body := fmt.Sprintf(`[{"name": "current", "start_date": "%s", "end_date": "%s"}]`, start, end)
+	expect(t, send(t, server, "POST", "/academic-years", body), http.StatusCreated, nil)
+
+	var added page[models.Term]
+	body = fmt.Sprintf(`[{"academic_year_id": 1, "name": "Autumn", "start_date": "%s", "end_date": "%s"}]`, start, end)
+	expect(t, send(t, server, "POST", "/terms", body), http.StatusCreated, &added)
+	return added.Data[0]
+}
+
+func TestStudentCRUD(t *testing.T) {
+	server := newServer(t, false)
+
+	var added page[models.Student]
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9a"}]`), http.StatusCreated, &added)
+	if added.Data[0].Class != "9A" || added.Data[0].ClassID != 1 {
+		t.Errorf("got %+v", added.Data[0])
+	}
+
+	var student models.Student
+	expect(t, send(t, server, "PATCH", "/students/1", `{"last_name": "Leigh"}`), http.StatusOK, &student)
+	if student.LastName != "Leigh" || student.Version != 2 {
+		t.Errorf("got %+v", student)
+	}
+
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9B"}]`), http.StatusConflict, nil)
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ben", "last_name": "Lee", "email": "ben.lee@school.test", "class": "10Z"}]`), http.StatusBadRequest, nil)
+
+	expect(t, send(t, server, "DELETE", "/students", `[{"id": 1, "version": 1}]`), http.StatusPreconditionFailed, nil)
+	expect(t, send(t, server, "DELETE", "/students", `[{"id": 1, "version": 2}]`), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/students/1", ""), http.StatusNotFound, nil)
+}
+
+func TestStudentClassMove(t *testing.T) {
+	server := newServer(t, false)
+	term := addTerm(t, server)
+	today := models.Today()
+
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9A"}]`), http.StatusCreated, nil)
+	expect(t, send(t, server, "POST", "/students/1/enrollments", `{"term_id": 1, "class_id": 1}`), http.StatusCreated, nil)
+	expect(t, send(t, server, "POST", "/students/1/enrollments", `{"term_id": 1, "class_id": 2}`), http.StatusConflict, nil)
+
+	expect(t, send(t, server, "PATCH", "/students/1", `{"class": "9B"}`), http.StatusOK, nil)
+
+	var history page[models.Enrollment]
+	expect(t, send(t, server, "GET", "/students/1/enrollments", ""), http.StatusOK, &history)
+	if history.Count != 2 {
+		t.Fatalf("got %+v", history.Data)
+	}
+	before, after := history.Data[0], history.Data[1]
+	if before.ClassID != 1 || !before.StartDate.Equal(term.StartDate.Time) || !before.EndDate.Equal(today.AddDate(0, 0, -1)) {
+		t.Errorf("got %+v before the move", before)
+	}
+	if after.ClassID != 2 || after.TermID != term.ID || !after.StartDate.Equal(today.Time) || !after.EndDate.Equal(term.EndDate.Time) {
+		t.Errorf("got %+v after the move", after)
+	}
+
+	// moving back on the same day corrects the enrollment of the day
+	expect(t, send(t, server, "PUT", "/students/1", `{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class_id": 1}`), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/students/1/enrollments", ""), http.StatusOK, &history)
+	if history.Count != 2 || history.Data[1].ClassID != 1 {
+		t.Errorf("got %+v after moving back", history.Data)
+	}
+
+	// the enrollments have to stay within the term
+	body := fmt.Sprintf(`{"academic_year_id": 1, "name": "Autumn", "start_date": "%s", "end_date": "%s"}`, term.StartDate, today)
+	expect(t, send(t, server, "PUT", "/terms/1", body), http.StatusBadRequest, nil)
+}
+
+func TestRecordAttendance(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9A"}]`), http.StatusCreated, nil)
+
+	// login is the email of teacher 1, of class 9A
+	var recorded page[models.Attendance]
+	expect(t, send(t, server, "POST", "/classes/1/attendance", `{"records": [{"student_id": 1, "status": "late"}]}`), http.StatusOK, &recorded)
+	if recorded.Count != 1 || recorded.Data[0].RecordedBy == nil || *recorded.Data[0].RecordedBy != 1 {
+		t.Errorf("got %+v", recorded.Data)
+	}
+
+	expect(t, send(t, server, "POST", "/classes/2/attendance", `{"records": [{"student_id": 1, "status": "late"}]}`), http.StatusForbidden, nil)
+}
+
+func TestStudentFields(t *testing.T) {
+	server := newServer(t, false)
+	expect(t, send(t, server, "POST", "/students", `[{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9A"}]`), http.StatusCreated, nil)
+
+	var list page[map[string]interface{}]
+	expect(t, send(t, server, "GET", "/students?fields=id,class", ""), http.StatusOK, &list)
+	if list.Count != 1 || len(list.Data[0]) != 2 || list.Data[0]["class"] != "9A" {
+		t.Errorf("got %v", list.Data)
+	}
+
+	expect(t, send(t, server, "GET", "/students?fields=password", ""), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "GET", "/students/1?fields=id,nickname", ""), http.StatusBadRequest, nil)
+}
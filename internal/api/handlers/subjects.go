@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strconv"
+)
+
+// subjectFilterFields declares the columns subjects can be filtered on and the operators each one allows
+var subjectFilterFields = utils.FilterFields{
+	"id":   utils.NumberOperators,
+	"name": utils.TextOperators,
+}
+
+func (h *Handler) GetSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := utils.ParseFilters(r, subjectFilterFields)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	fields, err := ParseFieldSet(r, models.Subject{})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, DbFieldNames(models.Subject{})),
+		Page:    page,
+		Limit:   limit,
+		Fields:  fields,
+	}
+
+	keyset, err := keysetOptions(r, &opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	subjectList, err := h.Subjects.List(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if keyset {
+		writeKeysetPage(w, r, opts, subjectList)
+		return
+	}
+
+	total, err := h.Subjects.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
+	response := struct {
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		Limit      int         `json:"limit"`
+		TotalPages int         `json:"total_pages"`
+		Data       interface{} `json:"data"`
+	}{
+		Status:     "success",
+		Count:      len(subjectList),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       projectList(subjectList, fields),
+	}
+
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	writeConditional(w, r, "", nil, response)
+}
+
+// GET /subjects/{id}
+func (h *Handler) GetOneSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Subject ID"))
+		return
+	}
+
+	subject, err := h.Subjects.Get(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Subject not found"))
+		return
+	}
+
+	writeConditional(w, r, "", nil, subject)
+}
+
+// POST /subjects
+func (h *Handler) AddSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	var newSubjects []models.Subject
+	var rawSubjects []map[string]interface{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
+		return
+	}
+
+	defer r.Body.Close()
+
+	err = json.Unmarshal(body, &rawSubjects)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	fields := CheckFieldNames(models.Subject{})
+	for _, subject := range rawSubjects {
+		for key := range subject {
+			if !utils.ContainsString(fields, key) {
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
+				return
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, &newSubjects)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	for i, subject := range newSubjects {
+		fieldErrors = append(fieldErrors, utils.AtIndex(utils.Validate(subject), i)...)
+	}
+	err = utils.ValidationFailed(fieldErrors)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	addedSubjects, err := h.Subjects.Create(r.Context(), newSubjects)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSubjects),
+		Data:   addedSubjects,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// PUT /subjects/{id} renames a subject
+func (h *Handler) UpdateSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Subject ID"))
+		return
+	}
+
+	var updatedSubject models.Subject
+	err = json.NewDecoder(r.Body).Decode(&updatedSubject)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
+		return
+	}
+
+	err = utils.ValidationFailed(utils.Validate(updatedSubject))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	updatedSubject.ID = id
+	updatedSubject, err = h.Subjects.Update(r.Context(), updatedSubject)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Subject not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSubject)
+}
+
+// DELETE /subjects/{id} answers 409 while the subject is assigned to a teacher
+func (h *Handler) DeleteOneSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Subject ID"))
+		return
+	}
+
+	err = h.Subjects.Delete(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Subject not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /teachers/{id}/subjects lists the subjects assigned to the teacher
+func (h *Handler) GetTeacherSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	teacher, ok := h.pathTeacher(w, r)
+	if !ok {
+		return
+	}
+
+	subjects, err := h.Subjects.ForTeacher(r.Context(), teacher.ID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	writeSubjects(w, r, subjects)
+}
+
+// PUT /teachers/{id}/subjects replaces the subjects of the teacher by the array of subject ids
+// in the body, which can't be empty. Subjects left out are unassigned; when that includes the
+// subject of the teacher, the first subject of the array takes its place.
+func (h *Handler) SetTeacherSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
+		return
+	}
+
+	var subjectIDs []int
+	err = json.NewDecoder(r.Body).Decode(&subjectIDs)
+	if err != nil || subjectIDs == nil {
+		utils.WriteError(w, r, utils.Validation("Invalid request payload, expected an array of subject ids"))
+		return
+	}
+	if len(subjectIDs) == 0 {
+		utils.WriteError(w, r, utils.Validation("a teacher needs at least one subject"))
+		return
+	}
+
+	subjects, err := h.Subjects.Assign(r.Context(), id, subjectIDs)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
+		return
+	}
+	writeSubjects(w, r, subjects)
+}
+
+func writeSubjects(w http.ResponseWriter, r *http.Request, subjects []models.Subject) {
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(subjects),
+		Data:   subjects,
+	}
+	writeConditional(w, r, "", nil, response)
+}
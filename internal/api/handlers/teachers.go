@@ -1,123 +1,152 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"reflect"
 	"restapi/internal/models"
-	"restapi/internal/repositories/sqlconnect"
+	"restapi/internal/repositories"
 	"restapi/pkg/utils"
 	"strconv"
+	"time"
 )
 
-func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
+// teacherFilterFields declares the columns teachers can be filtered on and the operators each one allows
+var teacherFilterFields = utils.FilterFields{
+	"id":         utils.NumberOperators,
+	"first_name": utils.TextOperators,
+	"last_name":  utils.TextOperators,
+	"email":      utils.TextOperators,
+	"class":      utils.TextOperators,
+	"class_id":   utils.NumberOperators,
+	"subject":    utils.TextOperators,
+}
+
+func (h *Handler) GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	h.listTeachers(w, r)
+}
+
+// listTeachers answers a GET of a teacher list, narrowed down to the teachers matching scope
+func (h *Handler) listTeachers(w http.ResponseWriter, r *http.Request, scope ...utils.Filter) {
+	filters, err := utils.ParseFilters(r, teacherFilterFields)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	filters = append(filters, scope...)
+
+	fields, err := ParseFieldSet(r, models.Teacher{})
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	withDeleted, err := includeDeleted(r)
 	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer db.Close()
 
-	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
-	var args []interface{}
+	page, limit := utils.ParsePagination(r)
+	opts := repositories.ListOptions{
+		Filters: filters,
+		Sort:    utils.ParseSorting(r, DbFieldNames(models.Teacher{})),
+		Page:    page,
+		Limit:   limit,
+		Fields:  fields,
 
-	query, args = utils.AddFilters(r, query, args)
+		IncludeDeleted: withDeleted,
+	}
 
-	query = utils.AddSorting(r, query)
+	keyset, err := keysetOptions(r, &opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-	rows, err := db.Query(query, args...)
+	teacherList, err := h.Teachers.List(r.Context(), opts)
 	if err != nil {
-		fmt.Println(err)
-		// http.Error(w, "Database query error", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer rows.Close()
 
-	teacherList := make([]models.Teacher, 0)
-	for rows.Next() {
-		var teacher models.Teacher
-		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
-		if err != nil {
-			// http.Error(w, "Error scanning database results", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error retrieving data")
-			return
-		}
-		teacherList = append(teacherList, teacher)
+	// cursor pages skip the COUNT, which is what makes them cheap on large tables
+	if keyset {
+		writeKeysetPage(w, r, opts, teacherList)
+		return
 	}
 
+	total, err := h.Teachers.Count(r.Context(), opts)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	totalPages := utils.TotalPages(total, limit)
+
 	response := struct {
-		Status string           `json:"status"`
-		Count  int              `json:"count"`
-		Data   []models.Teacher `json:"data"`
+		Status     string      `json:"status"`
+		Count      int         `json:"count"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		Limit      int         `json:"limit"`
+		TotalPages int         `json:"total_pages"`
+		Data       interface{} `json:"data"`
 	}{
-		Status: "success",
-		Count:  len(teacherList),
-		Data:   teacherList,
+		Status:     "success",
+		Count:      len(teacherList),
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+		Data:       projectList(teacherList, fields),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// a list has no single Last-Modified, a deleted row would not move it
+	w.Header().Set("Link", utils.LinkHeader(r, page, limit, totalPages))
+	writeConditional(w, r, "", nil, response)
 
 }
 
-func GetOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
-	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
-		return
-	}
-	defer db.Close()
-
+func (h *Handler) GetOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 
 	// Handle path parameter
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		fmt.Println(err)
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
 		return
 	}
 
-	var teacher models.Teacher
-
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
-
-	if err == sql.ErrNoRows {
-		// http.Error(w, "Teacher not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Teacher not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Database query error", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error retrieving data")
+	fields, err := ParseFieldSet(r, models.Teacher{})
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(teacher)
-}
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-// function for POST Teacher request handler
-func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
+	teacher, err := h.Teachers.Get(r.Context(), id, withDeleted, fields...)
 	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error adding data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
-	defer db.Close()
 
+	writeConditional(w, r, utils.ETag(teacher.Version), teacher.UpdatedAt, projectFields(teacher, fields))
+}
+
+// function for POST Teacher request handler
+func (h *Handler) AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	var newTeachers []models.Teacher
 	var rawTeachers []map[string]interface{}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Error reading Request body", http.StatusInternalServerError)
+		utils.WriteError(w, r, utils.Internal(err, "Error reading Request body"))
 		return
 	}
 
@@ -125,8 +154,7 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &rawTeachers)
 	if err != nil {
-		http.Error(w, "invalid Request body", http.StatusBadRequest)
-		utils.ErrorHandler(err, "invalid Request body")
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
 		return
 	}
 
@@ -141,7 +169,7 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		for key := range teacher {
 			_, ok := allowedFields[key]
 			if !ok {
-				http.Error(w, "Unacceptable fields found in request. Only use allowed fields..", http.StatusBadRequest)
+				utils.WriteError(w, r, utils.Validation("Unacceptable fields found in request. Only use allowed fields.."))
 				return
 			}
 
@@ -150,47 +178,40 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &newTeachers)
 	if err != nil {
-		http.Error(w, "invalid Request body", http.StatusBadRequest)
-		utils.ErrorHandler(err, "invalid Request body")
+		utils.WriteError(w, r, utils.Validation("invalid Request body"))
 		return
 	}
 
-	for _, teacher := range newTeachers {
-		err := CheckBlankFields(teacher)
+	partial, err := partialMode(r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if partial {
+		createPartial(w, r, newTeachers, h.validateMember, h.Teachers.CreatePartial)
+		return
+	}
+
+	// report every invalid field of every item at once
+	var fieldErrors []utils.FieldError
+	for i := range newTeachers {
+		invalid, err := h.validateMember(r.Context(), &newTeachers[i])
 		if err != nil {
-			// http.Error(w, "invalid Request body", http.StatusBadRequest)
-			utils.ErrorHandler(err, "invalid Request body")
+			utils.WriteError(w, r, err)
 			return
 		}
+		fieldErrors = append(fieldErrors, utils.AtIndex(invalid, i)...)
 	}
-
-	// stmt, err := db.Prepare("INSERT INTO teachers (first_name, last_name, email, class, subject) VALUES (?,?,?,?,?)")
-	stmt, err := db.Prepare(utils.GenerateInsertQuery("teachers", models.Teacher{}))
+	err = utils.ValidationFailed(fieldErrors)
 	if err != nil {
-		// http.Error(w, "Error preparing SQL query", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error adding data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer stmt.Close()
 
-	addedTeachers := make([]models.Teacher, len(newTeachers))
-	for i, newTeacher := range newTeachers {
-		// res, err := stmt.Exec(newTeacher.FirstName, newTeacher.LastName, newTeacher.Email, newTeacher.Class, newTeacher.Subject)
-		values := utils.GetStructValues(newTeacher)
-		res, err := stmt.Exec(values...)
-		if err != nil {
-			// http.Error(w, "Error inserting data into DB", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error adding data")
-			return
-		}
-		lastID, err := res.LastInsertId()
-		if err != nil {
-			// http.Error(w, "Error getting last insert ID", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error adding data")
-			return
-		}
-		newTeacher.ID = int(lastID)
-		addedTeachers[i] = newTeacher
+	addedTeachers, err := h.Teachers.Create(r.Context(), newTeachers)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -209,353 +230,273 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // PUT /teachers/{id}
-func UpdateTeacherHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid Teacher ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid teacher ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
 		return
 	}
 
-	var updatedTeader models.Teacher
-	err = json.NewDecoder(r.Body).Decode(&updatedTeader)
+	version, err := h.ifMatchVersion(r, h.teacherVersion(r.Context(), id))
 	if err != nil {
-		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	var updatedTeacher models.Teacher
+	err = json.NewDecoder(r.Body).Decode(&updatedTeacher)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, utils.Validation("Invalid Request Payload"))
 		return
 	}
-	defer db.Close()
 
-	var existingTeacher models.Teacher
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Teacher not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Teacher not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Unable to retrieve data", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+	invalid, err := h.validateMember(r.Context(), &updatedTeacher)
+	if err == nil {
+		err = utils.ValidationFailed(invalid)
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	updatedTeader.ID = existingTeacher.ID
-	_, err = db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", updatedTeader.FirstName, updatedTeader.LastName, updatedTeader.Email, updatedTeader.Class, updatedTeader.Subject, updatedTeader.ID)
+	updatedTeacher.ID = id
+	updatedTeacher.Version = version
+	updatedTeacher, err = h.Teachers.Update(r.Context(), updatedTeacher)
 	if err != nil {
-		// http.Error(w, "Unable to update teacher", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(updatedTeacher.Version))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedTeader)
+	json.NewEncoder(w).Encode(updatedTeacher)
 
 }
 
 // PATCH FOR MULTIPLE ENTRIES /teachers
-func PatchTeachersHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
-	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
-		return
-	}
-	defer db.Close()
+func (h *Handler) PatchTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
 
-	var updates []map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	patches, err := decodeBulkPatch[models.Teacher](r, "teacher", h.RequireIfMatch)
 	if err != nil {
-		// http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid request payload")
+		utils.WriteError(w, r, err)
 		return
 	}
+	for i := range patches {
+		patches[i].Prepare = h.prepareMemberPatch(r.Context())
+	}
 
-	// start transaction
-	tx, err := db.Begin()
+	_, err = h.Teachers.Patch(r.Context(), patches)
 	if err != nil {
-		// http.Error(w, "Error starting transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
 
-	for _, update := range updates {
-		idStr, ok := update["id"].(string)
-		if !ok {
-			tx.Rollback()
-			// http.Error(w, "Invalid teacher ID in update", http.StatusBadRequest)
-			utils.ErrorHandler(err, "Invalid teacher ID")
-			return
-		}
-
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error converting ID to int", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		var teacherFromDb models.Teacher
-		err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacherFromDb.ID, &teacherFromDb.FirstName, &teacherFromDb.LastName, &teacherFromDb.Email, &teacherFromDb.Class, &teacherFromDb.Subject)
-		if err != nil {
-			tx.Rollback()
-			if err == sql.ErrNoRows {
-				http.Error(w, "Teacher not found", http.StatusNotFound)
-				utils.ErrorHandler(err, "Teacher not found")
-			}
-			// http.Error(w, "Error retrieving Teacher", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
+// PATCH /teachers/{id}
+func (h *Handler) PatchOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
 
-		// apply updates using reflect
-		teacherVal := reflect.ValueOf(&teacherFromDb).Elem()
-		teacherType := teacherVal.Type()
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
+		return
+	}
 
-		for k, v := range update {
-			if k == "id" {
-				continue // skip updating the id field
-			}
-			for i := 0; i < teacherVal.NumField(); i++ {
-				field := teacherType.Field(i)
-				if field.Tag.Get("json") == k+",omitempty" {
-					fieldVal := teacherVal.Field(i)
-					if fieldVal.CanSet() {
-						val := reflect.ValueOf(v)
-						if val.Type().ConvertibleTo(fieldVal.Type()) {
-							fieldVal.Set(val.Convert(fieldVal.Type()))
-						} else {
-							tx.Rollback()
-							log.Printf("Cannot convert %v to %v", val.Type(), fieldVal.Type())
-							return
-						}
-					}
-					break
-				}
-			}
-		}
+	version, err := h.ifMatchVersion(r, h.teacherVersion(r.Context(), id))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 
-		_, err = tx.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", teacherFromDb.FirstName, teacherFromDb.LastName, teacherFromDb.Email, teacherFromDb.Class, teacherFromDb.Subject, teacherFromDb.ID)
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error updating teacher", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error updating data")
-			return
-		}
+	patch, err := decodePatch[models.Teacher](r, id, version)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
 	}
+	patch.Prepare = h.prepareMemberPatch(r.Context())
 
-	// commit the transaction
-	err = tx.Commit()
+	updatedTeachers, err := h.Teachers.Patch(r.Context(), []repositories.Patch{patch})
 	if err != nil {
-		// http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+
+	w.Header().Set("ETag", utils.ETag(updatedTeachers[0].Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedTeachers[0])
+
 }
 
-// PATCH /teachers/{id}
-func PatchOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
+// DELETE /teachers/{id}
+func (h *Handler) DeleteOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		// http.Error(w, "Invalid Teacher ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Teacher ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	version, err := h.ifMatchVersion(r, h.teacherVersion(r.Context(), id))
 	if err != nil {
-		// http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Request payload")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	err = h.Teachers.Delete(r.Context(), id, version)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
-	defer db.Close()
 
-	var existingTeacher models.Teacher
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&existingTeacher.ID, &existingTeacher.FirstName, &existingTeacher.LastName, &existingTeacher.Email, &existingTeacher.Class, &existingTeacher.Subject)
-	if err == sql.ErrNoRows {
-		// http.Error(w, "Teacher not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Teacher not found")
-		return
-	} else if err != nil {
-		// http.Error(w, "Unable to retrieve data", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+	w.WriteHeader(http.StatusNoContent)
+
+}
+
+// DELETE MULTIPLE TEACHERS /teachers
+func (h *Handler) DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	deletions, err := decodeBulkDelete(r, "teacher", h.RequireIfMatch)
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// apply updates using reflect
-	teacherVal := reflect.ValueOf(&existingTeacher).Elem()
-	teacherType := teacherVal.Type()
-
-	for k, v := range updates {
-		for i := 0; i < teacherVal.NumField(); i++ {
-			field := teacherType.Field(i)
-			field.Tag.Get("json")
-			if field.Tag.Get("json") == k+",omitempty" {
-				if teacherVal.Field(i).CanSet() {
-					teacherVal.Field(i).Set(reflect.ValueOf(v).Convert(teacherVal.Field(i).Type()))
-				}
-			}
-		}
+	if len(deletions) < 1 {
+		utils.WriteError(w, r, utils.Validation("IDs do not exist"))
+		return
 	}
 
-	_, err = db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingTeacher.FirstName, existingTeacher.LastName, existingTeacher.Email, existingTeacher.Class, existingTeacher.Subject, existingTeacher.ID)
+	deletedIds, err := h.Teachers.BulkDelete(r.Context(), deletions)
 	if err != nil {
-		// http.Error(w, "Unable to update teacher", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error updating data")
+		utils.WriteError(w, r, repoError(err, ""))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(existingTeacher)
+	response := struct {
+		Status     string `json:"status"`
+		DeletedIDs []int  `json:"deleted_ids"`
+	}{
+		Status:     "Teachers successfully deleted",
+		DeletedIDs: deletedIds,
+	}
 
+	json.NewEncoder(w).Encode(response)
 }
 
-// DELETE /teachers/{id}
-func DeleteOneTeacherHandler(w http.ResponseWriter, r *http.Request) {
+// POST /teachers/{id}/restore
+func (h *Handler) RestoreTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		// http.Error(w, "Invalid Teacher ID", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid Teacher ID")
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
 		return
 	}
 
-	db, err := sqlconnect.ConnectDb()
+	restoredTeacher, err := h.Teachers.Restore(r.Context(), id)
 	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
 		return
 	}
-	defer db.Close()
 
-	res, err := db.Exec("DELETE FROM teachers WHERE id = ?", id)
+	w.Header().Set("ETag", utils.ETag(restoredTeacher.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restoredTeacher)
+}
+
+// POST /teachers/purge permanently removes the teachers deleted longer than the retention period ago
+func (h *Handler) PurgeTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	deletedBefore := time.Now().UTC().Add(-h.PurgeRetention).Truncate(time.Second)
+	purged, err := h.Teachers.Purge(r.Context(), deletedBefore)
 	if err != nil {
-		// http.Error(w, "Error deleting teacher", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		// http.Error(w, "Error retrieving delete result", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
-		return
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status        string    `json:"status"`
+		Purged        int       `json:"purged"`
+		DeletedBefore time.Time `json:"deleted_before"`
+	}{
+		Status:        "success",
+		Purged:        purged,
+		DeletedBefore: deletedBefore,
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	if rowsAffected == 0 {
-		// http.Error(w, "Teacher not found", http.StatusNotFound)
-		utils.ErrorHandler(err, "Teacher not found")
+// GET /teachers/{id}/students lists the students in the teacher's class
+func (h *Handler) GetTeacherStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	teacher, ok := h.pathTeacher(w, r)
+	if !ok {
 		return
 	}
-
-	w.WriteHeader(http.StatusNoContent)
-
+	h.listStudents(w, r, classFilter(teacher.ClassID))
 }
 
-// DELETE MULTIPLE TEACHERS /teachers
-func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sqlconnect.ConnectDb()
-	if err != nil {
-		// http.Error(w, "Unable to connect to database", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+// GET /teachers/{id}/studentcount counts the students in the teacher's class
+func (h *Handler) GetTeacherStudentCountHandler(w http.ResponseWriter, r *http.Request) {
+	teacher, ok := h.pathTeacher(w, r)
+	if !ok {
 		return
 	}
-	defer db.Close()
 
-	var ids []int
-	err = json.NewDecoder(r.Body).Decode(&ids)
+	filters, err := utils.ParseFilters(r, studentFilterFields)
 	if err != nil {
-		// http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		utils.ErrorHandler(err, "Invalid request payload")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	tx, err := db.Begin()
+	withDeleted, err := includeDeleted(r)
 	if err != nil {
-		// http.Error(w, "Error starting transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	stmt, err := tx.Prepare("DELETE FROM teachers WHERE id = ?")
+	count, err := h.Students.Count(r.Context(), repositories.ListOptions{
+		Filters:        append(filters, classFilter(teacher.ClassID)),
+		IncludeDeleted: withDeleted,
+	})
 	if err != nil {
-		tx.Rollback()
-		// http.Error(w, "Error preparing delete statement", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
+		utils.WriteError(w, r, err)
 		return
 	}
-	defer stmt.Close()
-
-	deletedIds := []int{}
-	for _, id := range ids {
-		res, err := stmt.Exec(id)
-		if err != nil {
-			tx.Rollback()
-
-			// http.Error(w, "Error Deleting Teacher", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error deleting data")
-			return
-		}
-
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			tx.Rollback()
-			// http.Error(w, "Error retrieving deleted result", http.StatusInternalServerError)
-			utils.ErrorHandler(err, "Error deleting data")
-			return
-		}
 
-		// if teacher was deleted then add the deleted id to deletedIDs slice
-		if rowsAffected > 0 {
-			deletedIds = append(deletedIds, id)
-		}
-
-		if rowsAffected < 1 {
-			tx.Rollback()
-			// http.Error(w, fmt.Sprintf("ID %v does not exist", id), http.StatusInternalServerError)
-			utils.ErrorHandler(err, fmt.Sprintf("ID %v does not exist", id))
-			return
-		}
+	response := struct {
+		Status string `json:"status"`
+		Count  int    `json:"count"`
+	}{
+		Status: "success",
+		Count:  count,
 	}
+	writeConditional(w, r, "", nil, response)
+}
 
-	// commit
-	err = tx.Commit()
+// pathTeacher reads the teacher named by the id path value, writing the error response when it fails
+func (h *Handler) pathTeacher(w http.ResponseWriter, r *http.Request) (models.Teacher, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		// http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		utils.ErrorHandler(err, "Error deleting data")
-		return
+		utils.WriteError(w, r, utils.Validation("Invalid Teacher ID"))
+		return models.Teacher{}, false
 	}
 
-	if len(deletedIds) < 1 {
-		// http.Error(w, "IDs do not exists", http.StatusBadRequest)
-		utils.ErrorHandler(err, "IDs do not exist")
-		return
+	teacher, err := h.Teachers.Get(r.Context(), id, false)
+	if err != nil {
+		utils.WriteError(w, r, repoError(err, "Teacher not found"))
+		return models.Teacher{}, false
 	}
+	return teacher, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	response := struct {
-		Status     string `json:"status"`
-		DeletedIDs []int  `json:"deleted_ids"`
-	}{
-		Status:     "Teachers successfully deleted",
-		DeletedIDs: deletedIds,
+// teacherVersion reads the version of the live teacher with id, for ifMatchVersion
+func (h *Handler) teacherVersion(ctx context.Context, id int) func() (int, error) {
+	return func() (int, error) {
+		teacher, err := h.Teachers.Get(ctx, id, false)
+		return teacher.Version, repoError(err, "Teacher not found")
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
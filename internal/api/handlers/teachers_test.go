@@ -0,0 +1,483 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"restapi/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTeacherCRUD(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	var teacher models.Teacher
+	w := send(t, server, "GET", "/teachers/1", "")
+	expect(t, w, http.StatusOK, &teacher)
+	if teacher.FirstName != "Jo" || teacher.ClassID != 1 || teacher.Version != 1 {
+		t.Errorf("got %+v", teacher)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("got ETag %s, want \"1\"", etag)
+	}
+
+	w = send(t, server, "PUT", "/teachers/1", `{"first_name": "Joanna", "last_name": "Smith", "email": "jo.smith@school.test", "class_id": 2, "subject": "Math"}`)
+	expect(t, w, http.StatusOK, &teacher)
+	if teacher.FirstName != "Joanna" || teacher.Class != "9B" || teacher.Version != 2 {
+		t.Errorf("got %+v after PUT", teacher)
+	}
+
+	expect(t, send(t, server, "DELETE", "/teachers/1", ""), http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/teachers/1", ""), http.StatusNotFound, nil)
+
+	var list page[models.Teacher]
+	expect(t, send(t, server, "GET", "/teachers", ""), http.StatusOK, &list)
+	if list.Total != 4 {
+		t.Errorf("got %d teachers after the delete, want 4", list.Total)
+	}
+
+	expect(t, send(t, server, "POST", "/teachers/1/restore", ""), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/teachers/1", ""), http.StatusOK, nil)
+}
+
+func TestTeacherValidation(t *testing.T) {
+	server := newServer(t, false)
+
+	w := send(t, server, "POST", "/teachers", `[{"first_name": "Jo", "last_name": "Smith", "email": "not an email", "class": "7C"}]`)
+	expect(t, w, http.StatusBadRequest, nil)
+}
+
+func TestTeacherPages(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	var list page[models.Teacher]
+	w := send(t, server, "GET", "/teachers?sortby=first_name:asc&limit=2&page=2", "")
+	expect(t, w, http.StatusOK, &list)
+	if list.Total != 5 || list.Count != 2 || list.Data[0].FirstName != "Cy" || list.Data[1].FirstName != "Di" {
+		t.Errorf("got %+v", list)
+	}
+	if w.Header().Get("Link") == "" {
+		t.Error("got no Link header")
+	}
+
+	expect(t, send(t, server, "GET", "/teachers?last_name=Smith", ""), http.StatusOK, &list)
+	if list.Total != 2 {
+		t.Errorf("got %d teachers named Smith, want 2", list.Total)
+	}
+}
+
+func TestTeacherCursor(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	// last_name sorts Smith twice, so the cursor has to break the tie by id
+	var names []string
+	cursor := ""
+	for range 5 {
+		var list page[models.Teacher]
+		expect(t, send(t, server, "GET", "/teachers?sortby=last_name:asc&limit=2&cursor="+url.QueryEscape(cursor), ""), http.StatusOK, &list)
+		for _, teacher := range list.Data {
+			names = append(names, teacher.FirstName)
+		}
+		cursor = list.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	want := []string{"Cy", "Di", "Jo", "Bo", "Al"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("got %v, want %v", names, want)
+		}
+	}
+
+	var list page[models.Teacher]
+	expect(t, send(t, server, "GET", "/teachers?sortby=last_name:asc&limit=2&cursor=", ""), http.StatusOK, &list)
+	expect(t, send(t, server, "GET", "/teachers?sortby=first_name:asc&limit=2&cursor="+url.QueryEscape(list.NextCursor), ""), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "GET", "/teachers?sortby=last_name:asc&limit=2&cursor="+url.QueryEscape(list.NextCursor+"x"), ""), http.StatusBadRequest, nil)
+}
+
+func TestTeacherConflicts(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	w := send(t, server, "POST", "/teachers", `[{"first_name": "Jo", "last_name": "Smith", "email": "JO.SMITH@school.test", "class": "9A", "subject": "Math"}]`)
+	expect(t, w, http.StatusConflict, nil)
+
+	w = send(t, server, "PATCH", "/teachers/2", `{"email": "bo.smith@school.test"}`)
+	expect(t, w, http.StatusConflict, nil)
+
+	// the email of a soft deleted teacher is free to take
+	expect(t, send(t, server, "DELETE", "/teachers/3", ""), http.StatusNoContent, nil)
+	w = send(t, server, "PATCH", "/teachers/2", `{"email": "bo.smith@school.test"}`)
+	expect(t, w, http.StatusOK, nil)
+}
+
+func TestTeacherSoftDelete(t *testing.T) {
+	h := newHandler(t, false)
+	// purge everything deleted before a minute from now
+	h.PurgeRetention = -time.Minute
+	server := serve(h)
+	addTeachers(t, server)
+
+	expect(t, send(t, server, "DELETE", "/teachers", `[1, 3]`), http.StatusOK, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"first_name": "Joanna"}`), http.StatusNotFound, nil)
+	expect(t, send(t, server, "POST", "/teachers/2/restore", ""), http.StatusConflict, nil)
+
+	var list page[models.Teacher]
+	expect(t, send(t, server, "GET", "/teachers?include_deleted=true", ""), http.StatusOK, &list)
+	if list.Total != 5 {
+		t.Errorf("got %d teachers with the deleted ones, want 5", list.Total)
+	}
+
+	// a new teacher takes the email of teacher 1, which then can't be restored
+	expect(t, send(t, server, "POST", "/teachers", `[{"first_name": "Jo", "last_name": "Smyth", "email": "jo.smith@school.test", "class": "9A", "subject": "Math"}]`), http.StatusCreated, nil)
+	expect(t, send(t, server, "POST", "/teachers/1/restore", ""), http.StatusConflict, nil)
+
+	var teacher models.Teacher
+	expect(t, send(t, server, "POST", "/teachers/3/restore", ""), http.StatusOK, &teacher)
+	if teacher.DeletedAt != nil || teacher.Version != 3 {
+		t.Errorf("got %+v", teacher)
+	}
+
+	var purged struct {
+		Purged int `json:"purged"`
+	}
+	expect(t, send(t, server, "POST", "/teachers/purge", ""), http.StatusOK, &purged)
+	if purged.Purged != 1 {
+		t.Errorf("purged %d teachers, want 1", purged.Purged)
+	}
+	expect(t, send(t, server, "POST", "/teachers/1/restore", ""), http.StatusNotFound, nil)
+}
+
+func TestTeacherNotModified(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	w := send(t, server, "GET", "/teachers/1", "")
+	expect(t, w, http.StatusOK, nil)
+	etag := w.Header().Get("ETag")
+
+	w = send(t, server, "GET", "/teachers/1", "", "If-None-Match", etag)
+	expect(t, w, http.StatusNotModified, nil)
+	if w.Body.Len() != 0 {
+		t.Errorf("got a body with 304: %s", w.Body.String())
+	}
+
+	w = send(t, server, "GET", "/teachers", "")
+	expect(t, w, http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/teachers", "", "If-None-Match", w.Header().Get("ETag")), http.StatusNotModified, nil)
+
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"subject": "Chemistry"}`), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/teachers/1", "", "If-None-Match", etag), http.StatusOK, nil)
+}
+
+func TestTeacherPreconditions(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"subject": "Chemistry"}`, "If-Match", `"1"`), http.StatusOK, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"subject": "Physics"}`, "If-Match", `"1"`), http.StatusPreconditionFailed, nil)
+	expect(t, send(t, server, "DELETE", "/teachers/1", "", "If-Match", `"1"`), http.StatusPreconditionFailed, nil)
+	expect(t, send(t, server, "PATCH", "/teachers", `[{"id": 1, "version": 1, "subject": "Physics"}]`), http.StatusPreconditionFailed, nil)
+	expect(t, send(t, server, "DELETE", "/teachers", `[{"id": 1, "version": 1}, 2]`), http.StatusPreconditionFailed, nil)
+
+	// If-Match can list several tags, of which the current one must be a strong one
+	expect(t, send(t, server, "PATCH", "/teachers/2", `{"subject": "Physics"}`, "If-Match", `W/"1", "3"`), http.StatusPreconditionFailed, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/2", `{"subject": "Physics"}`, "If-Match", `"3", "1"`), http.StatusOK, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/2", `{"subject": "Math"}`, "If-Match", `"1", "2"`), http.StatusOK, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/9", `{"subject": "Math"}`, "If-Match", `"1", "2"`), http.StatusNotFound, nil)
+
+	// a failed bulk delete deletes none of them
+	expect(t, send(t, server, "GET", "/teachers/2", ""), http.StatusOK, nil)
+	expect(t, send(t, server, "DELETE", "/teachers/1", "", "If-Match", `"2"`), http.StatusNoContent, nil)
+}
+
+func TestTeacherStrictMode(t *testing.T) {
+	server := newServer(t, true)
+	addTeachers(t, server)
+
+	expect(t, send(t, server, "PUT", "/teachers/1", `{"first_name": "Jo", "last_name": "Smith", "email": "jo.smith@school.test", "class": "9A"}`), http.StatusPreconditionRequired, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"subject": "Chemistry"}`), http.StatusPreconditionRequired, nil)
+	expect(t, send(t, server, "DELETE", "/teachers/1", ""), http.StatusPreconditionRequired, nil)
+	expect(t, send(t, server, "PATCH", "/teachers", `[{"id": 1, "subject": "Physics"}]`), http.StatusPreconditionRequired, nil)
+	expect(t, send(t, server, "DELETE", "/teachers", `[1, 2]`), http.StatusPreconditionRequired, nil)
+	expect(t, send(t, server, "DELETE", "/teachers", `[{"id": 1, "version": 1}, 2]`), http.StatusPreconditionRequired, nil)
+
+	var deleted struct {
+		DeletedIDs []int `json:"deleted_ids"`
+	}
+	expect(t, send(t, server, "DELETE", "/teachers", `[{"id": 1, "version": 1}, {"id": "2", "version": 1}]`), http.StatusOK, &deleted)
+	if len(deleted.DeletedIDs) != 2 {
+		t.Errorf("got %v deleted", deleted.DeletedIDs)
+	}
+}
+
+func TestTeacherMergePatch(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	var teacher models.Teacher
+	w := send(t, server, "PATCH", "/teachers/1", `{"class": "9B", "subject": "Music"}`, "Content-Type", "application/merge-patch+json")
+	expect(t, w, http.StatusOK, &teacher)
+	if teacher.Class != "9B" || teacher.ClassID != 2 || teacher.Subject != "Music" || teacher.Version != 2 {
+		t.Errorf("got %+v", teacher)
+	}
+
+	// null clears the subject, which is required
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"subject": null}`, "Content-Type", "application/merge-patch+json"), http.StatusBadRequest, nil)
+
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"nickname": "J"}`, "Content-Type", "application/merge-patch+json"), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "PATCH", "/teachers/1", `{"class_id": 99}`, "Content-Type", "application/merge-patch+json"), http.StatusBadRequest, nil)
+
+	var list page[models.Teacher]
+	expect(t, send(t, server, "PATCH", "/teachers", `[{"id": 2, "subject": "Drama"}, {"id": "3", "last_name": "Smythe"}]`), http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/teachers?subject=Drama", ""), http.StatusOK, &list)
+	if list.Total != 1 || list.Data[0].ID != 2 {
+		t.Errorf("got %+v after the bulk patch", list.Data)
+	}
+}
+
+func TestTeacherJSONPatch(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	var teacher models.Teacher
+	w := send(t, server, "PATCH", "/teachers/1", `[
+		{"op": "test", "path": "/version", "value": 1},
+		{"op": "replace", "path": "/first_name", "value": "Joanna"},
+		{"op": "copy", "from": "/last_name", "path": "/subject"}
+	]`, "Content-Type", "application/json-patch+json")
+	expect(t, w, http.StatusOK, &teacher)
+	if teacher.FirstName != "Joanna" || teacher.Subject != "Smith" || teacher.Version != 2 {
+		t.Errorf("got %+v", teacher)
+	}
+
+	// a failing test applies none of the ops
+	w = send(t, server, "PATCH", "/teachers/1", `[
+		{"op": "replace", "path": "/first_name", "value": "Jo"},
+		{"op": "test", "path": "/version", "value": 1}
+	]`, "Content-Type", "application/json-patch+json")
+	expect(t, w, http.StatusConflict, nil)
+	expect(t, send(t, server, "GET", "/teachers/1", ""), http.StatusOK, &teacher)
+	if teacher.FirstName != "Joanna" {
+		t.Errorf("got %q after a failed test", teacher.FirstName)
+	}
+
+	w = send(t, server, "PATCH", "/teachers", `{
+		"2": [{"op": "replace", "path": "/subject", "value": "Music"}],
+		"3": [{"op": "test", "path": "/version", "value": 1}, {"op": "replace", "path": "/subject", "value": "Drama"}]
+	}`, "Content-Type", "application/json-patch+json")
+	expect(t, w, http.StatusNoContent, nil)
+	expect(t, send(t, server, "GET", "/teachers/3", ""), http.StatusOK, &teacher)
+	if teacher.Subject != "Drama" || teacher.Version != 2 {
+		t.Errorf("got %+v after the bulk patch", teacher)
+	}
+}
+
+func TestTeacherSubjectFilter(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	// the subjects of the teachers fill the catalogue, by first use
+	var subjects page[models.Subject]
+	expect(t, send(t, server, "GET", "/subjects?sortby=id:asc", ""), http.StatusOK, &subjects)
+	if subjects.Total != 4 || subjects.Data[0].Name != "Math" || subjects.Data[1].Name != "Art" {
+		t.Fatalf("got %+v", subjects.Data)
+	}
+
+	expect(t, send(t, server, "PUT", "/teachers/1/subjects", `[1, 2]`), http.StatusOK, nil)
+
+	var list page[models.Teacher]
+	expect(t, send(t, server, "GET", "/teachers?subject=Art&sortby=id:asc", ""), http.StatusOK, &list)
+	if list.Total != 2 || list.Data[0].ID != 1 || list.Data[1].ID != 2 {
+		t.Errorf("got %+v for subject=Art", list.Data)
+	}
+
+	// teacher 1 teaches Math and Art
+	expect(t, send(t, server, "GET", "/teachers?subject[ne]=Art", ""), http.StatusOK, &list)
+	if list.Total != 3 {
+		t.Errorf("got %d teachers for subject[ne]=Art, want 3", list.Total)
+	}
+
+	// unassigning Math takes it from the subject of teacher 1 too
+	expect(t, send(t, server, "PUT", "/teachers/1/subjects", `[2]`), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/teachers?subject=Math", ""), http.StatusOK, &list)
+	if list.Total != 1 || list.Data[0].ID != 4 {
+		t.Errorf("got %+v for subject=Math", list.Data)
+	}
+	var teacher models.Teacher
+	expect(t, send(t, server, "GET", "/teachers/1", ""), http.StatusOK, &teacher)
+	if teacher.Subject != "Art" || teacher.Version != 2 {
+		t.Errorf("got %+v after unassigning its subject", teacher)
+	}
+	expect(t, send(t, server, "PUT", "/teachers/1/subjects", `[]`), http.StatusBadRequest, nil)
+
+	// a new subject replaces the old one in the assignments
+	expect(t, send(t, server, "PATCH", "/teachers/2", `{"subject": "Drama"}`), http.StatusOK, nil)
+	expect(t, send(t, server, "GET", "/teachers?subject=Art", ""), http.StatusOK, &list)
+	if list.Total != 1 || list.Data[0].ID != 1 {
+		t.Errorf("got %+v for subject=Art after the patch", list.Data)
+	}
+	expect(t, send(t, server, "GET", "/teachers/2/subjects", ""), http.StatusOK, &subjects)
+	if subjects.Count != 1 || subjects.Data[0].Name != "Drama" {
+		t.Errorf("got %+v for teacher 2", subjects.Data)
+	}
+}
+
+func TestTeacherFields(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	var list page[map[string]interface{}]
+	expect(t, send(t, server, "GET", "/teachers?fields=id,first_name,email&sortby=last_name:asc&limit=2&cursor=", ""), http.StatusOK, &list)
+	if list.Count != 2 || list.NextCursor == "" {
+		t.Fatalf("got %+v", list)
+	}
+	for _, teacher := range list.Data {
+		if len(teacher) != 3 || teacher["id"] == nil || teacher["first_name"] == nil || teacher["email"] == nil {
+			t.Errorf("got %v, want only id, first_name and email", teacher)
+		}
+	}
+
+	// the cursor still pages by last_name, which was not asked for
+	expect(t, send(t, server, "GET", "/teachers?fields=id,first_name,email&sortby=last_name:asc&limit=2&cursor="+url.QueryEscape(list.NextCursor), ""), http.StatusOK, &list)
+	if list.Count != 2 || list.Data[0]["first_name"] != "Jo" || list.Data[1]["first_name"] != "Bo" {
+		t.Errorf("got %v on the second page", list.Data)
+	}
+
+	var teacher map[string]interface{}
+	expect(t, send(t, server, "GET", "/teachers/2?fields=subject", ""), http.StatusOK, &teacher)
+	if len(teacher) != 1 || teacher["subject"] != "Art" {
+		t.Errorf("got %v", teacher)
+	}
+
+	expect(t, send(t, server, "GET", "/teachers?fields=id,salary", ""), http.StatusBadRequest, nil)
+	expect(t, send(t, server, "GET", "/teachers/1?fields=", ""), http.StatusOK, &teacher)
+	if teacher["last_name"] != "Smith" {
+		t.Errorf("got %v for an empty fields", teacher)
+	}
+}
+
+func TestTeacherPartialCreate(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+
+	body := `[
+		{"first_name": "Ed", "last_name": "North", "email": "ed.north@school.test", "class": "9A", "subject": "Math"},
+		{"first_name": "Fi", "last_name": "South", "email": "not an email", "class": "9A", "subject": "Math"},
+		{"first_name": "Jo", "last_name": "Smith", "email": "jo.smith@school.test", "class": "9A", "subject": "Math"},
+		{"first_name": "Gu", "last_name": "East", "email": "ed.north@school.test", "class": "9B", "subject": "Art"},
+		{"first_name": "Hy", "last_name": "West", "email": "hy.west@school.test", "class": "10Z", "subject": "Art"}
+	]`
+
+	// without ?mode=partial one bad item fails the whole request
+	expect(t, send(t, server, "POST", "/teachers", body), http.StatusBadRequest, nil)
+	var list page[models.Teacher]
+	expect(t, send(t, server, "GET", "/teachers", ""), http.StatusOK, &list)
+	if list.Total != 5 {
+		t.Fatalf("got %d teachers after a failed create, want 5", list.Total)
+	}
+
+	var response struct {
+		Created int `json:"created"`
+		Failed  int `json:"failed"`
+		Results []struct {
+			Index  int                    `json:"index"`
+			Status int                    `json:"status"`
+			Data   *models.Teacher        `json:"data"`
+			Error  map[string]interface{} `json:"error"`
+		} `json:"results"`
+	}
+	expect(t, send(t, server, "POST", "/teachers?mode=partial", body), http.StatusMultiStatus, &response)
+	if response.Created != 1 || response.Failed != 4 || len(response.Results) != 5 {
+		t.Fatalf("got %+v", response)
+	}
+	want := []int{http.StatusCreated, http.StatusBadRequest, http.StatusConflict, http.StatusConflict, http.StatusBadRequest}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != want[i] {
+			t.Errorf("got item %d with status %d, want %d", result.Index, result.Status, want[i])
+		}
+		if (result.Status == http.StatusCreated) != (result.Data != nil) || (result.Status == http.StatusCreated) == (result.Error != nil) {
+			t.Errorf("got item %d with data %v and error %v", i, result.Data, result.Error)
+		}
+	}
+	if created := response.Results[0].Data; created.ID != 6 || created.ClassID != 1 {
+		t.Errorf("got %+v", created)
+	}
+
+	expect(t, send(t, server, "GET", "/teachers", ""), http.StatusOK, &list)
+	if list.Total != 6 {
+		t.Errorf("got %d teachers after a partial create, want 6", list.Total)
+	}
+
+	// a conflict rolls back the items before it
+	body = `[
+		{"first_name": "Ka", "last_name": "Lo", "email": "ka.lo@school.test", "class": "9A", "subject": "Math"},
+		{"first_name": "Ed", "last_name": "North", "email": "ed.north@school.test", "class": "9A", "subject": "Math"}
+	]`
+	expect(t, send(t, server, "POST", "/teachers", body), http.StatusConflict, nil)
+	expect(t, send(t, server, "GET", "/teachers?email=ka.lo@school.test", ""), http.StatusOK, &list)
+	if list.Total != 0 {
+		t.Errorf("got %+v created by a failed request", list.Data)
+	}
+
+	expect(t, send(t, server, "POST", "/teachers?mode=atomic", body), http.StatusBadRequest, nil)
+}
+
+func TestTeacherStudents(t *testing.T) {
+	server := newServer(t, false)
+	addTeachers(t, server)
+	body := `[
+		{"first_name": "Ann", "last_name": "Lee", "email": "ann.lee@school.test", "class": "9A"},
+		{"first_name": "Ben", "last_name": "Ho", "email": "ben.ho@school.test", "class": "9A"},
+		{"first_name": "Cat", "last_name": "Ng", "email": "cat.ng@school.test", "class": "9B"},
+		{"first_name": "Dan", "last_name": "Oh", "email": "dan.oh@school.test", "class": "9A"}
+	]`
+	expect(t, send(t, server, "POST", "/students", body), http.StatusCreated, nil)
+
+	// the students of 9A, where teacher 1 teaches, with the paging and sorting of /students
+	var students page[models.Student]
+	w := send(t, server, "GET", "/teachers/1/students?sortby=first_name:desc&limit=2", "")
+	expect(t, w, http.StatusOK, &students)
+	if students.Total != 3 || students.Count != 2 || students.Data[0].FirstName != "Dan" || students.Data[1].FirstName != "Ben" {
+		t.Errorf("got %+v", students)
+	}
+	if w.Header().Get("Link") == "" {
+		t.Error("got no Link header")
+	}
+
+	expect(t, send(t, server, "GET", "/teachers/1/students?last_name=Ng", ""), http.StatusOK, &students)
+	if students.Total != 0 {
+		t.Errorf("got %+v from another class", students.Data)
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	expect(t, send(t, server, "GET", "/teachers/3/studentcount", ""), http.StatusOK, &count)
+	if count.Count != 3 {
+		t.Errorf("got %d students for teacher 3, want 3", count.Count)
+	}
+	expect(t, send(t, server, "GET", "/teachers/2/studentcount?first_name=Cat", ""), http.StatusOK, &count)
+	if count.Count != 1 {
+		t.Errorf("got %d students named Cat for teacher 2, want 1", count.Count)
+	}
+
+	var teachers page[models.Teacher]
+	expect(t, send(t, server, "GET", "/students/3/teachers?sortby=id:asc", ""), http.StatusOK, &teachers)
+	if teachers.Total != 2 || teachers.Data[0].ID != 2 || teachers.Data[1].ID != 4 {
+		t.Errorf("got %+v", teachers.Data)
+	}
+
+	expect(t, send(t, server, "GET", "/teachers/9/students", ""), http.StatusNotFound, nil)
+	expect(t, send(t, server, "GET", "/teachers/9/studentcount", ""), http.StatusNotFound, nil)
+	expect(t, send(t, server, "GET", "/students/9/teachers", ""), http.StatusNotFound, nil)
+	expect(t, send(t, server, "GET", "/teachers/1/students?fields=salary", ""), http.StatusBadRequest, nil)
+}
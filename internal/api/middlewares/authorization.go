@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"restapi/pkg/utils"
+)
+
+// RequireRoles only lets the request through when the role claim put in the
+// context by JWTMiddleware is one of roles
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(utils.ContextKey("role")).(string)
+			if !ok || role == "" {
+				utils.WriteError(w, r, utils.Forbidden("no role found for the logged in user"))
+				return
+			}
+
+			if _, ok := allowed[role]; !ok {
+				utils.WriteError(w, r, utils.Forbidden(fmt.Sprintf("role %q is not allowed to %s %s", role, r.Method, r.URL.Path)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
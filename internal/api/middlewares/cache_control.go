@@ -0,0 +1,14 @@
+package middlewares
+
+import "net/http"
+
+// CacheControl replaces the no-store policy SecurityHeaders sets on every response with value,
+// for the routes it wraps, e.g. "private, no-cache" for responses clients may keep and revalidate
+func CacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
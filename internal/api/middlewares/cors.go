@@ -3,6 +3,7 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"restapi/pkg/utils"
 )
 
 // Allowed origins
@@ -21,13 +22,13 @@ func Cors(next http.Handler) http.Handler {
 		if isOriginAllowed(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		} else {
-			http.Error(w, "Not Allowed By CORS", http.StatusForbidden)
+			utils.WriteError(w, r, utils.Forbidden("Not Allowed By CORS"))
 			return
 		}
 		// w.Header().Set()
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "Authorization")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, If-Match, If-None-Match, If-Modified-Since")
+		w.Header().Set("Access-Control-Expose-Headers", "Authorization, X-Request-ID, ETag, Last-Modified, Link, Accept-Patch")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
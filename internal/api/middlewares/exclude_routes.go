@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MiddlewaresExcludePaths skips the middleware for a request whose path is one of excludedPaths.
+// Like a ServeMux pattern, an excluded path ending in a slash covers the paths below it as well.
+func MiddlewaresExcludePaths(middleware func(http.Handler) http.Handler, excludedPaths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withMiddleware := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range excludedPaths {
+				if r.URL.Path == path || (strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			withMiddleware.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	mw "restapi/internal/api/middlewares"
+	"testing"
+)
+
+func TestMiddlewaresExcludePaths(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := mw.MiddlewaresExcludePaths(deny, "/execs/login", "/execs/resetpassword/reset/")(ok)
+
+	for path, want := range map[string]int{
+		"/execs/login":                    http.StatusOK,
+		"/execs/login/":                   http.StatusUnauthorized,
+		"/execs/loginx":                   http.StatusUnauthorized,
+		"/execs/login/../../teachers":     http.StatusUnauthorized,
+		"/execs/resetpassword/reset/abc":  http.StatusOK,
+		"/execs/resetpassword/reset":      http.StatusUnauthorized,
+		"/execs/resetpassword/resetx/abc": http.StatusUnauthorized,
+		"/teachers":                       http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("%s: got status %d, want %d", path, w.Code, want)
+		}
+	}
+}
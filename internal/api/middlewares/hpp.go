@@ -72,7 +72,11 @@ func filterQueryParams(r *http.Request, whitelist []string) {
 
 }
 
+// isWhiteListed also accepts operator filters like first_name[like] when first_name is whitelisted
 func isWhiteListed(param string, whitelist []string) bool {
+	if field, _, found := strings.Cut(param, "["); found && strings.HasSuffix(param, "]") {
+		param = field
+	}
 	for _, v := range whitelist {
 		if param == v {
 			return true
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"restapi/pkg/utils"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func JWTMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the token can come from the Authorization header or from the cookie set on login
+		tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if tokenString == "" {
+			token, err := r.Cookie("Bearer")
+			if err != nil {
+				utils.WriteError(w, r, utils.Unauthorized("Authorization Header Missing"))
+				return
+			}
+			tokenString = token.Value
+		}
+
+		// a token signed with an empty key proves nothing
+		jwtSecret := os.Getenv("JWT_SECRET")
+		if jwtSecret == "" {
+			utils.WriteError(w, r, utils.Unauthorized("Invalid Login Token"))
+			return
+		}
+
+		parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(jwtSecret), nil
+		})
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				utils.WriteError(w, r, utils.Unauthorized("Token Expired"))
+				return
+			}
+			utils.WriteError(w, r, utils.Unauthorized("Invalid Login Token"))
+			return
+		}
+
+		claims, ok := parsedToken.Claims.(jwt.MapClaims)
+		if !ok || !parsedToken.Valid {
+			utils.WriteError(w, r, utils.Unauthorized("Invalid Login Token"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), utils.ContextKey("role"), claims["role"])
+		ctx = context.WithValue(ctx, utils.ContextKey("expiresAt"), claims["exp"])
+		ctx = context.WithValue(ctx, utils.ContextKey("username"), claims["user"])
+		ctx = context.WithValue(ctx, utils.ContextKey("email"), claims["email"])
+		ctx = context.WithValue(ctx, utils.ContextKey("userId"), claims["uid"])
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
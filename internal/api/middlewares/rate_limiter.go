@@ -3,6 +3,7 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"restapi/pkg/utils"
 	"sync"
 	"time"
 )
@@ -46,7 +47,7 @@ func (rl *rateLimiter) Middleware(next http.Handler) http.Handler {
 		fmt.Printf("Visitor Count from %v is %v\n", visitorIP, rl.visitors[visitorIP])
 
 		if rl.visitors[visitorIP] > rl.limit {
-			http.Error(w, "Too many request", http.StatusTooManyRequests)
+			utils.WriteError(w, r, utils.NewError(http.StatusTooManyRequests, "Too many request"))
 			return
 		}
 
@@ -3,37 +3,118 @@ package routers
 import (
 	"net/http"
 	"restapi/internal/api/handlers"
+	mw "restapi/internal/api/middlewares"
+	"restapi/internal/models"
 )
 
-func Router() *http.ServeMux {
+func MainRouter(h *handlers.Handler) http.Handler {
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /", handlers.RootHandler)
+	// ROLE POLICIES
+	// GET routes are open to any logged in exec, the JWT middleware already guarantees that
+	adminOnly := mw.RequireRoles(models.RoleAdmin)
+	managers := mw.RequireRoles(models.RoleAdmin, models.RoleManager)
+	anyRole := mw.RequireRoles(models.RoleAdmin, models.RoleManager, models.RoleExec)
+
+	// CACHE POLICIES
+	// everything else is no-store, these responses carry an ETag and may be kept and revalidated
+	revalidate := mw.CacheControl("private, no-cache")
+
+	mux.HandleFunc("GET /{$}", handlers.RootHandler)
+
+	// SEARCH ROUTER
+	mux.HandleFunc("GET /search", h.SearchHandler)
 
 	// TEACHERS ROUTER
-	mux.HandleFunc("GET /teachers", handlers.GetTeachersHandler)
-	mux.HandleFunc("POST /teachers", handlers.AddTeacherHandler)
-	mux.HandleFunc("PATCH /teachers", handlers.PatchTeachersHandler)
-	mux.HandleFunc("DELETE /teachers", handlers.DeleteTeachersHandler)
+	mux.Handle("GET /teachers", revalidate(http.HandlerFunc(h.GetTeachersHandler)))
+	mux.Handle("POST /teachers", managers(http.HandlerFunc(h.AddTeacherHandler)))
+	mux.Handle("PATCH /teachers", managers(http.HandlerFunc(h.PatchTeachersHandler)))
+	mux.Handle("DELETE /teachers", adminOnly(http.HandlerFunc(h.DeleteTeachersHandler)))
 
-	mux.HandleFunc("PUT /teachers/{id}", handlers.UpdateTeacherHandler)
-	mux.HandleFunc("PATCH /teachers/{id}", handlers.PatchOneTeacherHandler)
-	mux.HandleFunc("GET /teachers/{id}", handlers.GetOneTeacherHandler)
-	mux.HandleFunc("DELETE /teachers/{id}", handlers.DeleteOneTeacherHandler)
+	mux.Handle("PUT /teachers/{id}", managers(http.HandlerFunc(h.UpdateTeacherHandler)))
+	mux.Handle("PATCH /teachers/{id}", managers(http.HandlerFunc(h.PatchOneTeacherHandler)))
+	mux.Handle("GET /teachers/{id}", revalidate(http.HandlerFunc(h.GetOneTeacherHandler)))
+	mux.Handle("GET /teachers/{id}/students", revalidate(http.HandlerFunc(h.GetTeacherStudentsHandler)))
+	mux.Handle("GET /teachers/{id}/studentcount", revalidate(http.HandlerFunc(h.GetTeacherStudentCountHandler)))
+	mux.Handle("GET /teachers/{id}/subjects", revalidate(http.HandlerFunc(h.GetTeacherSubjectsHandler)))
+	mux.Handle("PUT /teachers/{id}/subjects", managers(http.HandlerFunc(h.SetTeacherSubjectsHandler)))
+	mux.Handle("DELETE /teachers/{id}", adminOnly(http.HandlerFunc(h.DeleteOneTeacherHandler)))
+	mux.Handle("POST /teachers/{id}/restore", adminOnly(http.HandlerFunc(h.RestoreTeacherHandler)))
+	mux.Handle("POST /teachers/purge", adminOnly(http.HandlerFunc(h.PurgeTeachersHandler)))
 
 	// STUDENTS ROUTER
-	mux.HandleFunc("GET /students", handlers.GetStudentsHandler)
-	mux.HandleFunc("POST /students", handlers.AddStudentHandler)
-	mux.HandleFunc("PATCH /students", handlers.PatchStudentsHandler)
-	mux.HandleFunc("DELETE /students", handlers.DeleteStudentsHandler)
+	mux.Handle("GET /students", revalidate(http.HandlerFunc(h.GetStudentsHandler)))
+	mux.Handle("POST /students", managers(http.HandlerFunc(h.AddStudentHandler)))
+	mux.Handle("PATCH /students", managers(http.HandlerFunc(h.PatchStudentsHandler)))
+	mux.Handle("DELETE /students", adminOnly(http.HandlerFunc(h.DeleteStudentsHandler)))
+
+	mux.Handle("PUT /students/{id}", managers(http.HandlerFunc(h.UpdateStudentHandler)))
+	mux.Handle("PATCH /students/{id}", managers(http.HandlerFunc(h.PatchOneStudentHandler)))
+	mux.Handle("GET /students/{id}", revalidate(http.HandlerFunc(h.GetOneStudentHandler)))
+	mux.Handle("GET /students/{id}/teachers", revalidate(http.HandlerFunc(h.GetStudentTeachersHandler)))
+	mux.Handle("GET /students/{id}/enrollments", revalidate(http.HandlerFunc(h.GetStudentEnrollmentsHandler)))
+	mux.Handle("POST /students/{id}/enrollments", managers(http.HandlerFunc(h.AddStudentEnrollmentHandler)))
+	mux.Handle("GET /students/{id}/attendance", revalidate(http.HandlerFunc(h.GetStudentAttendanceHandler)))
+	mux.Handle("GET /students/{id}/attendance/summary", revalidate(http.HandlerFunc(h.GetStudentAttendanceSummaryHandler)))
+	mux.Handle("DELETE /students/{id}", adminOnly(http.HandlerFunc(h.DeleteOneStudentHandler)))
+	mux.Handle("POST /students/{id}/restore", adminOnly(http.HandlerFunc(h.RestoreStudentHandler)))
+	mux.Handle("POST /students/purge", adminOnly(http.HandlerFunc(h.PurgeStudentsHandler)))
+
+	// CLASSES ROUTER
+	mux.Handle("GET /classes", revalidate(http.HandlerFunc(h.GetClassesHandler)))
+	mux.Handle("POST /classes", managers(http.HandlerFunc(h.AddClassesHandler)))
+
+	mux.Handle("PUT /classes/{id}", managers(http.HandlerFunc(h.UpdateClassHandler)))
+	mux.Handle("PATCH /classes/{id}", managers(http.HandlerFunc(h.PatchOneClassHandler)))
+	mux.Handle("GET /classes/{id}", revalidate(http.HandlerFunc(h.GetOneClassHandler)))
+	mux.Handle("GET /classes/{id}/teachers", revalidate(http.HandlerFunc(h.GetClassTeachersHandler)))
+	mux.Handle("GET /classes/{id}/students", revalidate(http.HandlerFunc(h.GetClassStudentsHandler)))
+	mux.Handle("DELETE /classes/{id}", adminOnly(http.HandlerFunc(h.DeleteOneClassHandler)))
+	mux.Handle("POST /classes/{id}/promote", managers(http.HandlerFunc(h.PromoteClassHandler)))
+	// every role may take a roll-call, the handler then only lets teachers of the class through
+	mux.Handle("POST /classes/{id}/attendance", anyRole(http.HandlerFunc(h.RecordClassAttendanceHandler)))
+	mux.Handle("GET /classes/{id}/attendance/summary", revalidate(http.HandlerFunc(h.GetClassAttendanceSummaryHandler)))
+
+	// SUBJECTS ROUTER
+	mux.Handle("GET /subjects", revalidate(http.HandlerFunc(h.GetSubjectsHandler)))
+	mux.Handle("POST /subjects", managers(http.HandlerFunc(h.AddSubjectsHandler)))
+
+	mux.Handle("PUT /subjects/{id}", managers(http.HandlerFunc(h.UpdateSubjectHandler)))
+	mux.Handle("GET /subjects/{id}", revalidate(http.HandlerFunc(h.GetOneSubjectHandler)))
+	mux.Handle("DELETE /subjects/{id}", adminOnly(http.HandlerFunc(h.DeleteOneSubjectHandler)))
+
+	// ACADEMIC YEARS ROUTER
+	mux.Handle("GET /academic-years", revalidate(http.HandlerFunc(h.GetAcademicYearsHandler)))
+	mux.Handle("POST /academic-years", managers(http.HandlerFunc(h.AddAcademicYearsHandler)))
+
+	mux.Handle("PUT /academic-years/{id}", managers(http.HandlerFunc(h.UpdateAcademicYearHandler)))
+	mux.Handle("GET /academic-years/{id}", revalidate(http.HandlerFunc(h.GetOneAcademicYearHandler)))
+	mux.Handle("GET /academic-years/{id}/terms", revalidate(http.HandlerFunc(h.GetAcademicYearTermsHandler)))
+	mux.Handle("DELETE /academic-years/{id}", adminOnly(http.HandlerFunc(h.DeleteOneAcademicYearHandler)))
+
+	// TERMS ROUTER
+	mux.Handle("GET /terms", revalidate(http.HandlerFunc(h.GetTermsHandler)))
+	mux.Handle("POST /terms", managers(http.HandlerFunc(h.AddTermsHandler)))
+
+	mux.Handle("PUT /terms/{id}", managers(http.HandlerFunc(h.UpdateTermHandler)))
+	mux.Handle("GET /terms/{id}", revalidate(http.HandlerFunc(h.GetOneTermHandler)))
+	mux.Handle("DELETE /terms/{id}", adminOnly(http.HandlerFunc(h.DeleteOneTermHandler)))
+
+	// EXECS ROUTER
+	mux.Handle("GET /execs", managers(http.HandlerFunc(h.GetExecsHandler)))
+	mux.Handle("POST /execs", adminOnly(http.HandlerFunc(h.AddExecsHandler)))
+	mux.Handle("PATCH /execs", adminOnly(http.HandlerFunc(h.PatchExecsHandler)))
+	mux.Handle("DELETE /execs", adminOnly(http.HandlerFunc(h.DeleteExecsHandler)))
 
-	mux.HandleFunc("PUT /students/{id}", handlers.UpdateStudentHandler)
-	mux.HandleFunc("PATCH /students/{id}", handlers.PatchOneStudentHandler)
-	mux.HandleFunc("GET /students/{id}", handlers.GetOneStudentHandler)
-	mux.HandleFunc("DELETE /students/{id}", handlers.DeleteOneStudentHandler)
+	mux.Handle("GET /execs/{id}", managers(http.HandlerFunc(h.GetOneExecHandler)))
+	mux.Handle("PATCH /execs/{id}", adminOnly(http.HandlerFunc(h.PatchOneExecHandler)))
+	mux.Handle("DELETE /execs/{id}", adminOnly(http.HandlerFunc(h.DeleteOneExecHandler)))
 
-	mux.HandleFunc("/execs", handlers.ExecsHandler)
+	mux.HandleFunc("POST /execs/login", h.LoginHandler)
+	mux.HandleFunc("POST /execs/logout", h.LogoutHandler)
+	mux.HandleFunc("POST /execs/forgotpassword", h.ForgotPasswordHandler)
+	mux.HandleFunc("POST /execs/resetpassword/reset/{resetcode}", h.ResetPasswordHandler)
 
-	return mux
+	return handlers.Unmatched(mux)
 }
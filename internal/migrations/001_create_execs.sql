@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS execs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    password_changed_at DATETIME NULL,
+    user_created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    password_reset_token VARCHAR(255) NULL,
+    password_token_expires DATETIME NULL,
+    inactive_status BOOLEAN NOT NULL DEFAULT FALSE,
+    role VARCHAR(50) NOT NULL DEFAULT 'exec',
+    INDEX idx_password_reset_token (password_reset_token)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS execs;
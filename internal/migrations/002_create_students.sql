@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS students (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    class VARCHAR(255) NOT NULL,
+    INDEX idx_class (class)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS students;
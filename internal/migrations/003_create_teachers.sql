@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS teachers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    class VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    INDEX idx_class (class),
+    INDEX idx_subject (subject)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS teachers;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE teachers ADD FULLTEXT INDEX ft_teachers (first_name, last_name, email, class, subject);
+ALTER TABLE students ADD FULLTEXT INDEX ft_students (first_name, last_name, email, class);
+
+-- +migrate Down
+ALTER TABLE students DROP INDEX ft_students;
+ALTER TABLE teachers DROP INDEX ft_teachers;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- fails if duplicate emails already exist, merge or fix those rows first
+ALTER TABLE teachers ADD UNIQUE INDEX uq_teachers_email (email);
+ALTER TABLE students ADD UNIQUE INDEX uq_students_email (email);
+
+-- +migrate Down
+ALTER TABLE students DROP INDEX uq_students_email;
+ALTER TABLE teachers DROP INDEX uq_teachers_email;
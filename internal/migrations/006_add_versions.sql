@@ -0,0 +1,8 @@
+-- +migrate Up
+-- version is bumped on every update and sent as the ETag, existing rows start at 1
+ALTER TABLE teachers ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE students ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- +migrate Down
+ALTER TABLE students DROP COLUMN version;
+ALTER TABLE teachers DROP COLUMN version;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- updated_at is sent as Last-Modified, the server sets it on every write
+ALTER TABLE teachers ADD COLUMN updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE students ADD COLUMN updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- +migrate Down
+ALTER TABLE students DROP COLUMN updated_at;
+ALTER TABLE teachers DROP COLUMN updated_at;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- deleted rows keep their data until they are purged, NULL means not deleted
+ALTER TABLE teachers ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_teachers_deleted_at (deleted_at);
+ALTER TABLE students ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_students_deleted_at (deleted_at);
+
+-- +migrate Down
+ALTER TABLE students DROP INDEX idx_students_deleted_at, DROP COLUMN deleted_at;
+ALTER TABLE teachers DROP INDEX idx_teachers_deleted_at, DROP COLUMN deleted_at;
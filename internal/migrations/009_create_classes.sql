@@ -0,0 +1,36 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS classes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    grade_level INT NOT NULL,
+    room VARCHAR(20) NOT NULL DEFAULT '',
+    capacity INT NULL,
+    homeroom_teacher_id INT NULL,
+    UNIQUE INDEX uq_classes_name (name),
+    CONSTRAINT chk_classes_name CHECK (name <> ''),
+    CONSTRAINT chk_classes_grade_level CHECK (grade_level BETWEEN 1 AND 12),
+    CONSTRAINT chk_classes_capacity CHECK (capacity BETWEEN 1 AND 200),
+    CONSTRAINT fk_classes_homeroom_teacher FOREIGN KEY (homeroom_teacher_id) REFERENCES teachers(id) ON DELETE SET NULL
+);
+
+-- name is as wide as the class column it is filled from, and capacity is NULL, no limit, until
+-- one is set.
+-- one class per distinct class string, the case insensitive collation folds "9a" into "9A".
+-- fails on the checks if a class is blank or does not start with a grade from 1 to 12, like
+-- "Band"; fix those rows first, the INSERT adds no class when it fails
+INSERT INTO classes (name, grade_level)
+SELECT UPPER(name), IF(name REGEXP '^[0-9]+', CAST(REGEXP_SUBSTR(name, '^[0-9]+') AS UNSIGNED), 0)
+FROM (SELECT TRIM(class) AS name FROM teachers UNION SELECT TRIM(class) FROM students) AS names;
+
+ALTER TABLE teachers ADD COLUMN class_id INT NULL AFTER class;
+UPDATE teachers JOIN classes ON classes.name = TRIM(teachers.class) SET teachers.class_id = classes.id, teachers.class = classes.name;
+ALTER TABLE teachers MODIFY class_id INT NOT NULL, ADD CONSTRAINT fk_teachers_class FOREIGN KEY (class_id) REFERENCES classes(id);
+
+ALTER TABLE students ADD COLUMN class_id INT NULL AFTER class;
+UPDATE students JOIN classes ON classes.name = TRIM(students.class) SET students.class_id = classes.id, students.class = classes.name;
+ALTER TABLE students MODIFY class_id INT NOT NULL, ADD CONSTRAINT fk_students_class FOREIGN KEY (class_id) REFERENCES classes(id);
+
+-- +migrate Down
+ALTER TABLE students DROP FOREIGN KEY fk_students_class, DROP COLUMN class_id;
+ALTER TABLE teachers DROP FOREIGN KEY fk_teachers_class, DROP COLUMN class_id;
+DROP TABLE IF EXISTS classes;
@@ -0,0 +1,27 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS subjects (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    UNIQUE INDEX uq_subjects_name (name)
+);
+
+-- deleting a teacher for good drops its assignments, a subject that is still assigned cannot be deleted
+CREATE TABLE IF NOT EXISTS teacher_subjects (
+    teacher_id INT NOT NULL,
+    subject_id INT NOT NULL,
+    PRIMARY KEY (teacher_id, subject_id),
+    INDEX idx_teacher_subjects_subject (subject_id),
+    CONSTRAINT fk_teacher_subjects_teacher FOREIGN KEY (teacher_id) REFERENCES teachers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_teacher_subjects_subject FOREIGN KEY (subject_id) REFERENCES subjects(id)
+);
+
+-- the subject of every teacher becomes its first assignment
+INSERT INTO subjects (name)
+SELECT DISTINCT TRIM(subject) FROM teachers WHERE TRIM(subject) <> '';
+
+INSERT INTO teacher_subjects (teacher_id, subject_id)
+SELECT teachers.id, subjects.id FROM teachers JOIN subjects ON subjects.name = TRIM(teachers.subject);
+
+-- +migrate Down
+DROP TABLE IF EXISTS teacher_subjects;
+DROP TABLE IF EXISTS subjects;
@@ -0,0 +1,41 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS academic_years (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(20) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    UNIQUE INDEX uq_academic_years_name (name)
+);
+
+CREATE TABLE IF NOT EXISTS terms (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    academic_year_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    UNIQUE INDEX uq_terms_year_name (academic_year_id, name),
+    INDEX idx_terms_start_date (start_date),
+    CONSTRAINT fk_terms_academic_year FOREIGN KEY (academic_year_id) REFERENCES academic_years(id)
+);
+
+-- purging a student drops its history, a term or class with enrollments cannot be deleted.
+-- the enrollments of a student do not overlap, which the repository checks with the student locked
+CREATE TABLE IF NOT EXISTS enrollments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    term_id INT NOT NULL,
+    class_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    INDEX idx_enrollments_student (student_id, start_date),
+    INDEX idx_enrollments_term (term_id),
+    INDEX idx_enrollments_class (class_id),
+    CONSTRAINT fk_enrollments_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_term FOREIGN KEY (term_id) REFERENCES terms(id),
+    CONSTRAINT fk_enrollments_class FOREIGN KEY (class_id) REFERENCES classes(id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS enrollments;
+DROP TABLE IF EXISTS terms;
+DROP TABLE IF EXISTS academic_years;
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- one roll-call per student, class and day, taking it again replaces it. Purging a student drops
+-- its attendance, a class with attendance cannot be deleted. recorded_by is the teacher of the
+-- class who took the roll-call, cleared when that teacher is purged.
+CREATE TABLE IF NOT EXISTS attendance (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    class_id INT NOT NULL,
+    date DATE NOT NULL,
+    status ENUM('present', 'absent', 'late', 'excused') NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    recorded_by INT NULL,
+    recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX uq_attendance_student_class_date (student_id, class_id, date),
+    INDEX idx_attendance_class_date (class_id, date),
+    CONSTRAINT fk_attendance_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_class FOREIGN KEY (class_id) REFERENCES classes(id),
+    CONSTRAINT fk_attendance_recorded_by FOREIGN KEY (recorded_by) REFERENCES teachers(id) ON DELETE SET NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS attendance;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- emails are only unique among live records, so the email of a deleted record can be reused.
+-- live_email is NULL once deleted_at is set, and a unique index allows any number of NULLs.
+-- Restoring a record whose email was reused fails until one of the two is changed.
+ALTER TABLE teachers ADD COLUMN live_email VARCHAR(255) AS (IF(deleted_at IS NULL, email, NULL)) STORED;
+ALTER TABLE teachers DROP INDEX uq_teachers_email, ADD UNIQUE INDEX uq_teachers_email (live_email);
+ALTER TABLE students ADD COLUMN live_email VARCHAR(255) AS (IF(deleted_at IS NULL, email, NULL)) STORED;
+ALTER TABLE students DROP INDEX uq_students_email, ADD UNIQUE INDEX uq_students_email (live_email);
+
+-- +migrate Down
+-- fails if a live and a deleted record share an email, purge or change one of them first
+ALTER TABLE students DROP INDEX uq_students_email, ADD UNIQUE INDEX uq_students_email (email);
+ALTER TABLE students DROP COLUMN live_email;
+ALTER TABLE teachers DROP INDEX uq_teachers_email, ADD UNIQUE INDEX uq_teachers_email (email);
+ALTER TABLE teachers DROP COLUMN live_email;
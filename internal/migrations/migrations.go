@@ -0,0 +1,291 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+// Migration is one versioned NNN_name.sql file, split into its up and down sections
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied, nil if it is pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations and records them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load reads and parses the embedded migration files, sorted by version
+func load() ([]Migration, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, rest, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must look like 001_description.sql", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, err := splitSections(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(rest, path.Ext(rest)),
+			Up:      up,
+			Down:    down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func splitSections(content string) (string, string, error) {
+	upIndex := strings.Index(content, upMarker)
+	downIndex := strings.Index(content, downMarker)
+	if upIndex == -1 || downIndex == -1 || downIndex < upIndex {
+		return "", "", fmt.Errorf("expected an %q section followed by a %q section", upMarker, downMarker)
+	}
+	up := content[upIndex+len(upMarker) : downIndex]
+	down := content[downIndex+len(downMarker):]
+	return strings.TrimSpace(up), strings.TrimSpace(down), nil
+}
+
+// statements splits a section into single statements, since the driver runs one statement per Exec.
+// Statements end with a semicolon at the end of a line.
+func statements(section string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(section, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		stmts = append(stmts, strings.TrimSpace(current.String()))
+	}
+	return stmts
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			return m.rollback(ctx, m.migrations[i])
+		}
+	}
+	return nil
+}
+
+// To migrates up or down until exactly the migrations with a version <= version are applied
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	// roll back newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			err := m.rollback(ctx, migration)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			err := m.apply(ctx, migration)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// DDL statements commit implicitly in MariaDB, so a migration that fails half way
+// has to be fixed by hand before it can be re-run
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	fmt.Printf("Applying migration %03d_%s\n", migration.Version, migration.Name)
+	for _, stmt := range statements(migration.Up) {
+		_, err := m.db.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("migration %03d_%s up: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err := m.db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name)
+	return err
+}
+
+func (m *Migrator) rollback(ctx context.Context, migration Migration) error {
+	fmt.Printf("Rolling back migration %03d_%s\n", migration.Version, migration.Name)
+	for _, stmt := range statements(migration.Down) {
+		_, err := m.db.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("migration %03d_%s down: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err := m.db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	return err
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the versions have to run without gaps, in order
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("got version %d at position %d, want %d", migration.Version, i, i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %03d_%s has an empty section", migration.Version, migration.Name)
+		}
+		if len(statements(migration.Up)) == 0 || len(statements(migration.Down)) == 0 {
+			t.Errorf("migration %03d_%s has a section without statements", migration.Version, migration.Name)
+		}
+	}
+
+	if len(migrations) < 13 || migrations[12].Name != "live_unique_emails" {
+		t.Errorf("got %d migrations, want 013_live_unique_emails among them", len(migrations))
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	up, down, err := splitSections("-- +migrate Up\nCREATE TABLE a (id INT);\n\n-- +migrate Down\nDROP TABLE a;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up != "CREATE TABLE a (id INT);" || down != "DROP TABLE a;" {
+		t.Errorf("got up %q and down %q", up, down)
+	}
+
+	for _, content := range []string{
+		"CREATE TABLE a (id INT);",
+		"-- +migrate Up\nCREATE TABLE a (id INT);",
+		"-- +migrate Down\nDROP TABLE a;\n-- +migrate Up\nCREATE TABLE a (id INT);",
+	} {
+		if _, _, err := splitSections(content); err == nil {
+			t.Errorf("got no error for %q", content)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	section := `-- a comment
+CREATE TABLE a (
+    id INT
+);
+
+ALTER TABLE a ADD COLUMN name VARCHAR(255);
+INSERT INTO a (id) VALUES (1)`
+
+	got := statements(section)
+	want := []string{"CREATE TABLE a (\n    id INT\n);", "ALTER TABLE a ADD COLUMN name VARCHAR(255);", "INSERT INTO a (id) VALUES (1)"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+// AcademicYear is a school year, like 2025-2026, divided into terms
+type AcademicYear struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty" validate:"required,max=20"`
+	StartDate Date   `json:"start_date" db:"start_date,omitempty" validate:"required"`
+	EndDate   Date   `json:"end_date" db:"end_date,omitempty" validate:"required"`
+}
+
+// Term is a part of an academic year. Students are enrolled in a class for each term.
+type Term struct {
+	ID             int    `json:"id,omitempty" db:"id,omitempty"`
+	AcademicYearID int    `json:"academic_year_id,omitempty" db:"academic_year_id,omitempty" validate:"required"`
+	Name           string `json:"name,omitempty" db:"name,omitempty" validate:"required,max=50"`
+	StartDate      Date   `json:"start_date" db:"start_date,omitempty" validate:"required"`
+	EndDate        Date   `json:"end_date" db:"end_date,omitempty" validate:"required"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// attendance statuses of a student on a day
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// Attendance is the roll-call of a student in a class on a day. RecordedBy is the teacher of the
+// class who took it, and is nil once that teacher is purged.
+type Attendance struct {
+	ID         int        `json:"id,omitempty" db:"id,omitempty"`
+	StudentID  int        `json:"student_id,omitempty" db:"student_id,omitempty" validate:"required"`
+	ClassID    int        `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Date       Date       `json:"date" db:"date,omitempty"`
+	Status     string     `json:"status,omitempty" db:"status,omitempty" validate:"required,oneof=present|absent|late|excused"`
+	Note       string     `json:"note,omitempty" db:"note,omitempty" validate:"max=255"`
+	RecordedBy *int       `json:"recorded_by,omitempty" db:"recorded_by,omitempty"`
+	RecordedAt *time.Time `json:"recorded_at,omitempty" db:"recorded_at,omitempty"`
+}
+
+// AttendanceSummary counts the days of a student, or of a whole class when StudentID is 0, by
+// status. Rate is the share of the days the student was there, late included, leaving excused
+// days out. It is nil while there are no such days.
+type AttendanceSummary struct {
+	StudentID int      `json:"student_id,omitempty"`
+	Days      int      `json:"days"`
+	Present   int      `json:"present"`
+	Absent    int      `json:"absent"`
+	Late      int      `json:"late"`
+	Excused   int      `json:"excused"`
+	Rate      *float64 `json:"rate"`
+}
+
+// Count adds the number of days with status
+func (s *AttendanceSummary) Count(status string, days int) {
+	s.Days += days
+	switch status {
+	case AttendancePresent:
+		s.Present += days
+	case AttendanceAbsent:
+		s.Absent += days
+	case AttendanceLate:
+		s.Late += days
+	case AttendanceExcused:
+		s.Excused += days
+	}
+	s.Rate = nil
+	if counted := s.Days - s.Excused; counted > 0 {
+		rate := math.Round(float64(s.Present+s.Late)/float64(counted)*10000) / 10000
+		s.Rate = &rate
+	}
+}
+
+// Add counts the days of other into s
+func (s *AttendanceSummary) Add(other AttendanceSummary) {
+	s.Count(AttendancePresent, other.Present)
+	s.Count(AttendanceAbsent, other.Absent)
+	s.Count(AttendanceLate, other.Late)
+	s.Count(AttendanceExcused, other.Excused)
+}
@@ -0,0 +1,12 @@
+package models
+
+// Class is a class teachers teach and students are in. Teachers and students refer to it by
+// ClassID and carry its Name as their class.
+type Class struct {
+	ID                int    `json:"id,omitempty" db:"id,omitempty"`
+	Name              string `json:"name,omitempty" db:"name,omitempty" validate:"required,pattern=class"`
+	GradeLevel        int    `json:"grade_level,omitempty" db:"grade_level,omitempty" validate:"required,min=1,max=12"`
+	Room              string `json:"room,omitempty" db:"room,omitempty" validate:"max=20"`
+	Capacity          *int   `json:"capacity,omitempty" db:"capacity,omitempty" validate:"min=1,max=200"`
+	HomeroomTeacherID *int   `json:"homeroom_teacher_id,omitempty" db:"homeroom_teacher_id,omitempty"`
+}
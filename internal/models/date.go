@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how a Date is written in JSON, query params and SQL
+const DateLayout = time.DateOnly
+
+// Date is a calendar day stored in a DATE column and sent as "2006-01-02". The zero Date is
+// sent as null.
+type Date struct {
+	time.Time
+}
+
+// ParseDate reads a "2006-01-02" day
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DateLayout, value)
+	return Date{t}, err
+}
+
+// Today is the current day in UTC
+func Today() Date {
+	return Date{time.Now().UTC().Truncate(24 * time.Hour)}
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	if value == nil || *value == "" {
+		*d = Date{}
+		return nil
+	}
+	*d, err = ParseDate(*value)
+	return err
+}
+
+// Scan reads a DATE, which the driver returns as a time.Time with parseTime=true
+func (d *Date) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case time.Time:
+		*d = Date{value.UTC().Truncate(24 * time.Hour)}
+		return nil
+	case []byte:
+		return d.scanString(string(value))
+	case string:
+		return d.scanString(value)
+	}
+	return fmt.Errorf("cannot scan %T into a Date", src)
+}
+
+func (d *Date) scanString(value string) error {
+	var err error
+	*d, err = ParseDate(value)
+	return err
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
@@ -0,0 +1,13 @@
+package models
+
+// Enrollment records the class a student was in during a term, from StartDate to EndDate, which
+// lie within the term. The enrollments of a student do not overlap, so a student who changes class
+// during a term has one for each class.
+type Enrollment struct {
+	ID        int  `json:"id,omitempty" db:"id,omitempty"`
+	StudentID int  `json:"student_id,omitempty" db:"student_id,omitempty"`
+	TermID    int  `json:"term_id,omitempty" db:"term_id,omitempty" validate:"required"`
+	ClassID   int  `json:"class_id,omitempty" db:"class_id,omitempty" validate:"required"`
+	StartDate Date `json:"start_date" db:"start_date,omitempty"`
+	EndDate   Date `json:"end_date" db:"end_date,omitempty"`
+}
@@ -1,4 +1,25 @@
 package models
 
+import "time"
+
 type Exec struct {
+	ID                   int        `json:"id,omitempty" db:"id,omitempty"`
+	FirstName            string     `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName             string     `json:"last_name,omitempty" db:"last_name,omitempty"`
+	Email                string     `json:"email,omitempty" db:"email,omitempty"`
+	Username             string     `json:"username,omitempty" db:"username,omitempty"`
+	Password             string     `json:"password,omitempty" db:"password,omitempty"`
+	PasswordChangedAt    *time.Time `json:"password_changed_at,omitempty" db:"password_changed_at,omitempty"`
+	UserCreatedAt        *time.Time `json:"user_created_at,omitempty" db:"user_created_at,omitempty"`
+	PasswordResetToken   *string    `json:"-" db:"password_reset_token,omitempty"`
+	PasswordTokenExpires *time.Time `json:"-" db:"password_token_expires,omitempty"`
+	InactiveStatus       bool       `json:"inactive_status" db:"inactive_status"`
+	Role                 string     `json:"role,omitempty" db:"role,omitempty" validate:"oneof=admin|manager|exec"`
 }
+
+// roles an exec can hold, carried in the "role" claim of the login token
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleExec    = "exec"
+)
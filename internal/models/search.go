@@ -0,0 +1,14 @@
+package models
+
+const (
+	SearchTypeTeacher = "teacher"
+	SearchTypeStudent = "student"
+)
+
+// SearchResult is one ranked hit of GET /search. Data holds the Teacher or Student named by Type.
+type SearchResult struct {
+	Type  string      `json:"type"`
+	ID    int         `json:"id"`
+	Score float64     `json:"score"`
+	Data  interface{} `json:"data"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Subject is an entry of the subject catalogue. Teachers are assigned any number of subjects,
+// next to the subject they carry themselves.
+type Subject struct {
+	ID   int    `json:"id,omitempty" db:"id,omitempty"`
+	Name string `json:"name,omitempty" db:"name,omitempty" validate:"required,max=100"`
+}
@@ -1,10 +1,16 @@
 package models
 
+import "time"
+
 type Teacher struct {
-	ID        int    `json:"id,omitempty" db:"id,omitempty"`
-	FirstName string `json:"first_name,omitempty" db:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty" db:"last_name,omitempty"`
-	Email     string `json:"email,omitempty" db:"email,omitempty"`
-	Class     string `json:"class,omitempty" db:"class,omitempty"`
-	Subject   string `json:"subject,omitempty" db:"subject,omitempty"`
+	ID        int        `json:"id,omitempty" db:"id,omitempty"`
+	FirstName string     `json:"first_name,omitempty" db:"first_name,omitempty" validate:"required,max=100"`
+	LastName  string     `json:"last_name,omitempty" db:"last_name,omitempty" validate:"required,max=100"`
+	Email     string     `json:"email,omitempty" db:"email,omitempty" validate:"required,email,max=255"`
+	Class     string     `json:"class,omitempty" db:"class,omitempty" validate:"required"`
+	ClassID   int        `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Subject   string     `json:"subject,omitempty" db:"subject,omitempty" validate:"required,max=100"`
+	Version   int        `json:"version,omitempty" db:"version,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at,omitempty"`
 }
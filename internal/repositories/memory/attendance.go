@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"sort"
+)
+
+// AttendanceRepo is an in-memory repositories.AttendanceRepository. Like the foreign keys, it
+// refuses records of students missing from the student table it is given, and keeps the classes
+// it is given from being deleted while they have attendance.
+type AttendanceRepo struct {
+	attendance *table[models.Attendance]
+	students   *StudentRepo
+}
+
+var _ repositories.AttendanceRepository = (*AttendanceRepo)(nil)
+
+func NewAttendanceRepo(students *StudentRepo, classes *ClassRepo) *AttendanceRepo {
+	a := &AttendanceRepo{
+		attendance: newTable(
+			func(a models.Attendance) int { return a.ID },
+			func(a *models.Attendance, id int) { a.ID = id },
+		),
+		students: students,
+	}
+	classes.attendance = a
+	return a
+}
+
+// Record replaces a record of the same student, class and day in place, keeping its id
+func (a *AttendanceRepo) Record(ctx context.Context, records []models.Attendance) ([]models.Attendance, error) {
+	for _, record := range records {
+		if _, err := a.students.Get(ctx, record.StudentID, true); err != nil {
+			return nil, utils.ValidationFailed([]utils.FieldError{{Field: "student_id", Rule: "exists", Message: "does not refer to an existing record"}})
+		}
+	}
+
+	a.attendance.mu.Lock()
+	defer a.attendance.mu.Unlock()
+
+	existing := make(map[attendanceKey]int)
+	for id, row := range a.attendance.rows {
+		existing[keyOf(row)] = id
+	}
+
+	now := repositories.Timestamp()
+	recorded := make([]models.Attendance, len(records))
+	for i, record := range records {
+		id, ok := existing[keyOf(record)]
+		if !ok {
+			id = a.attendance.nextID
+			a.attendance.nextID++
+			existing[keyOf(record)] = id
+		}
+		record.ID, record.RecordedAt = id, now
+		a.attendance.rows[id] = record
+		recorded[i] = record
+	}
+	sortAttendance(recorded)
+	return recorded, nil
+}
+
+// attendanceKey is the unique key of a record
+type attendanceKey struct {
+	studentID, classID int
+	date               string
+}
+
+func keyOf(record models.Attendance) attendanceKey {
+	return attendanceKey{record.StudentID, record.ClassID, record.Date.String()}
+}
+
+func (a *AttendanceRepo) List(ctx context.Context, q repositories.AttendanceQuery) ([]models.Attendance, error) {
+	a.attendance.mu.RLock()
+	defer a.attendance.mu.RUnlock()
+
+	list := make([]models.Attendance, 0)
+	for _, row := range a.attendance.rows {
+		if matchesAttendance(row, q) {
+			list = append(list, row)
+		}
+	}
+	sortAttendance(list)
+	return list, nil
+}
+
+func (a *AttendanceRepo) Summarize(ctx context.Context, q repositories.AttendanceQuery) ([]models.AttendanceSummary, error) {
+	list, err := a.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent := make(map[int]*models.AttendanceSummary)
+	summaries := make([]models.AttendanceSummary, 0)
+	for _, row := range list {
+		if byStudent[row.StudentID] == nil {
+			byStudent[row.StudentID] = &models.AttendanceSummary{StudentID: row.StudentID}
+		}
+		byStudent[row.StudentID].Count(row.Status, 1)
+	}
+	for _, summary := range byStudent {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].StudentID < summaries[j].StudentID })
+	return summaries, nil
+}
+
+func matchesAttendance(row models.Attendance, q repositories.AttendanceQuery) bool {
+	return (q.StudentID == 0 || row.StudentID == q.StudentID) &&
+		(q.ClassID == 0 || row.ClassID == q.ClassID) &&
+		(q.From.IsZero() || !row.Date.Before(q.From.Time)) &&
+		(q.To.IsZero() || !row.Date.After(q.To.Time))
+}
+
+// sortAttendance orders list by date, then class, like the SQL queries
+func sortAttendance(list []models.Attendance) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Date.Equal(list[j].Date.Time) {
+			return list[i].Date.Before(list[j].Date.Time)
+		}
+		if list[i].ClassID != list[j].ClassID {
+			return list[i].ClassID < list[j].ClassID
+		}
+		return list[i].ID < list[j].ID
+	})
+}
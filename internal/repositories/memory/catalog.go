@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+)
+
+// catalog is an in-memory repositories.CatalogRepository named like its SQL table. Its rows are
+// deleted for good, and inUse, when set, stands in for the foreign keys pointing at them.
+type catalog[T any] struct {
+	*table[T]
+	name  string
+	inUse func(id int) bool
+}
+
+func (c *catalog[T]) Get(ctx context.Context, id int, fields ...string) (T, error) {
+	return c.table.Get(ctx, id, false, fields...)
+}
+
+func (c *catalog[T]) Delete(ctx context.Context, id int) error {
+	// checked before the lock is taken, like countMembers in ClassRepo.Delete
+	inUse := c.inUse != nil && c.inUse(id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.rows[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	if inUse {
+		return fmt.Errorf("%w: other records still refer to %s %d", repositories.ErrInUse, c.name, id)
+	}
+	delete(c.rows, id)
+	return nil
+}
+
+// AcademicYearRepo is an in-memory repositories.AcademicYearRepository
+type AcademicYearRepo struct {
+	*catalog[models.AcademicYear]
+}
+
+var _ repositories.AcademicYearRepository = (*AcademicYearRepo)(nil)
+
+func NewAcademicYearRepo() *AcademicYearRepo {
+	return &AcademicYearRepo{&catalog[models.AcademicYear]{
+		table: newTable(
+			func(y models.AcademicYear) int { return y.ID },
+			func(y *models.AcademicYear, id int) { y.ID = id },
+			"name",
+		),
+		name: "academic_years",
+	}}
+}
+
+// TermRepo is an in-memory repositories.TermRepository. It keeps the academic years it is given
+// from being deleted while they have terms.
+type TermRepo struct {
+	*catalog[models.Term]
+}
+
+var _ repositories.TermRepository = (*TermRepo)(nil)
+
+func NewTermRepo(years *AcademicYearRepo) *TermRepo {
+	terms := &TermRepo{&catalog[models.Term]{
+		table: newTable(
+			func(t models.Term) int { return t.ID },
+			func(t *models.Term, id int) { t.ID = id },
+		),
+		name: "terms",
+	}}
+	terms.sameKey = func(a, b models.Term) bool {
+		return a.AcademicYearID == b.AcademicYearID && compareText(a.Name, b.Name) == 0
+	}
+	terms.duplicate = "the academic year already has a term with this name"
+
+	years.inUse = func(id int) bool {
+		return countRows(terms.table, func(t models.Term) bool { return t.AcademicYearID == id }) > 0
+	}
+	return terms
+}
+
+// countRows counts the rows of t that match
+func countRows[T any](t *table[T], match func(T) bool) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	count := 0
+	for _, row := range t.rows {
+		if match(row) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"strings"
+)
+
+// ClassRepo is an in-memory repositories.ClassRepository. It checks the members of a class in the
+// teacher and student tables it is given, standing in for their foreign keys.
+type ClassRepo struct {
+	classes  *table[models.Class]
+	teachers *TeacherRepo
+	students *StudentRepo
+	// enrollments and attendance are set by NewEnrollmentRepo and NewAttendanceRepo
+	enrollments *EnrollmentRepo
+	attendance  *AttendanceRepo
+}
+
+var _ repositories.ClassRepository = (*ClassRepo)(nil)
+
+func NewClassRepo(teachers *TeacherRepo, students *StudentRepo) *ClassRepo {
+	return &ClassRepo{
+		classes: newTable(
+			func(c models.Class) int { return c.ID },
+			func(c *models.Class, id int) { c.ID = id },
+			"name",
+		),
+		teachers: teachers,
+		students: students,
+	}
+}
+
+func (c *ClassRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Class, error) {
+	return c.classes.List(ctx, opts)
+}
+
+func (c *ClassRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	return c.classes.Count(ctx, opts)
+}
+
+func (c *ClassRepo) Get(ctx context.Context, id int, fields ...string) (models.Class, error) {
+	return c.classes.Get(ctx, id, false, fields...)
+}
+
+func (c *ClassRepo) GetByName(ctx context.Context, name string) (models.Class, error) {
+	c.classes.mu.RLock()
+	defer c.classes.mu.RUnlock()
+
+	for _, class := range c.classes.rows {
+		if strings.EqualFold(class.Name, name) {
+			return class, nil
+		}
+	}
+	return models.Class{}, repositories.ErrNotFound
+}
+
+func (c *ClassRepo) Create(ctx context.Context, classes []models.Class) ([]models.Class, error) {
+	return c.classes.Create(ctx, classes)
+}
+
+func (c *ClassRepo) Update(ctx context.Context, class models.Class) (models.Class, error) {
+	updated, err := c.classes.Update(ctx, class)
+	if err != nil {
+		return models.Class{}, err
+	}
+	renameMembers(c.teachers.table, updated)
+	renameMembers(c.students.table, updated)
+	return updated, nil
+}
+
+func (c *ClassRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Class, error) {
+	updated, err := c.classes.Patch(ctx, patches)
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range updated {
+		renameMembers(c.teachers.table, class)
+		renameMembers(c.students.table, class)
+	}
+	return updated, nil
+}
+
+// Delete removes the class for good, classes are not soft deleted. Deleted members still count,
+// like they do for the foreign keys. The members are counted before the class table is locked,
+// since a teacher or student patch reads classes while it holds its own table's lock.
+func (c *ClassRepo) Delete(ctx context.Context, id int) error {
+	teachers, students := countMembers(c.teachers.table, id), countMembers(c.students.table, id)
+
+	c.classes.mu.Lock()
+	defer c.classes.mu.Unlock()
+
+	if _, ok := c.classes.rows[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	if teachers > 0 || students > 0 {
+		return fmt.Errorf("%w: class %d has %d teacher(s) and %d student(s), counting deleted ones", repositories.ErrInUse, id, teachers, students)
+	}
+	if (c.enrollments != nil && countMembers(c.enrollments.enrollments, id) > 0) || (c.attendance != nil && countMembers(c.attendance.attendance, id) > 0) {
+		return fmt.Errorf("%w: class %d is in the enrollment or attendance history", repositories.ErrInUse, id)
+	}
+	delete(c.classes.rows, id)
+	return nil
+}
+
+// countMembers counts the rows of t in the class with classID
+func countMembers[T any](t *table[T], classID int) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	count := 0
+	for _, row := range t.rows {
+		if reflect.ValueOf(row).FieldByName("ClassID").Int() == int64(classID) {
+			count++
+		}
+	}
+	return count
+}
+
+// renameMembers copies the name of class to the rows of t in it, as a write to each row it changes
+func renameMembers[T any](t *table[T], class models.Class) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, row := range t.rows {
+		value := reflect.ValueOf(&row).Elem()
+		if value.FieldByName("ClassID").Int() != int64(class.ID) || value.FieldByName("Class").String() == class.Name {
+			continue
+		}
+		value.FieldByName("Class").SetString(class.Name)
+		deleted := deletedAt(row)
+		stamp(&row, version(row)+1)
+		setDeletedAt(&row, deleted)
+		t.rows[id] = row
+	}
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnrollmentRepo is an in-memory repositories.EnrollmentRepository over the student table it is
+// given. It keeps the terms and classes it is given from being deleted while they have enrollments,
+// and moves the enrollment of a student whose class changes.
+type EnrollmentRepo struct {
+	enrollments *table[models.Enrollment]
+	students    *StudentRepo
+	terms       *TermRepo
+	classes     *ClassRepo
+}
+
+var _ repositories.EnrollmentRepository = (*EnrollmentRepo)(nil)
+
+func NewEnrollmentRepo(students *StudentRepo, terms *TermRepo, classes *ClassRepo) *EnrollmentRepo {
+	e := &EnrollmentRepo{
+		enrollments: newTable(
+			func(e models.Enrollment) int { return e.ID },
+			func(e *models.Enrollment, id int) { e.ID = id },
+		),
+		students: students,
+		terms:    terms,
+		classes:  classes,
+	}
+
+	// the students lock is held, like in Promote
+	students.updated = func(before, after models.Student) {
+		if after.ClassID != before.ClassID {
+			e.move(after.ID, after.ClassID, models.Today())
+		}
+	}
+	terms.inUse = func(id int) bool {
+		return countRows(e.enrollments, func(e models.Enrollment) bool { return e.TermID == id }) > 0
+	}
+	classes.enrollments = e
+	return e
+}
+
+func (e *EnrollmentRepo) ForStudent(ctx context.Context, studentID int) ([]models.Enrollment, error) {
+	return e.matching(func(enrollment models.Enrollment) bool { return enrollment.StudentID == studentID }), nil
+}
+
+func (e *EnrollmentRepo) ForTerm(ctx context.Context, termID int) ([]models.Enrollment, error) {
+	return e.matching(func(enrollment models.Enrollment) bool { return enrollment.TermID == termID }), nil
+}
+
+// matching returns the enrollments that match, oldest first
+func (e *EnrollmentRepo) matching(match func(models.Enrollment) bool) []models.Enrollment {
+	e.enrollments.mu.RLock()
+	defer e.enrollments.mu.RUnlock()
+
+	enrollments := make([]models.Enrollment, 0)
+	for _, enrollment := range e.enrollments.rows {
+		if match(enrollment) {
+			enrollments = append(enrollments, enrollment)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool {
+		if !enrollments[i].StartDate.Equal(enrollments[j].StartDate.Time) {
+			return enrollments[i].StartDate.Before(enrollments[j].StartDate.Time)
+		}
+		return enrollments[i].ID < enrollments[j].ID
+	})
+	return enrollments
+}
+
+// Create reads the class before it locks the students, then the enrollments, like Promote
+func (e *EnrollmentRepo) Create(ctx context.Context, enrollment models.Enrollment) (models.Enrollment, error) {
+	class, err := e.classes.Get(ctx, enrollment.ClassID)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+
+	e.students.mu.Lock()
+	defer e.students.mu.Unlock()
+	e.enrollments.mu.Lock()
+	defer e.enrollments.mu.Unlock()
+
+	if e.enrolledDuring(enrollment.StudentID, enrollment.StartDate, enrollment.EndDate) {
+		return models.Enrollment{}, utils.Conflict(fmt.Sprintf("student %d is already enrolled between %s and %s", enrollment.StudentID, enrollment.StartDate, enrollment.EndDate))
+	}
+	enrollment = e.insert(enrollment)
+
+	today := models.Today()
+	if student, ok := e.students.live(enrollment.StudentID); ok && !enrollment.StartDate.After(today.Time) && !enrollment.EndDate.Before(today.Time) {
+		e.moveStudent(student, class)
+	}
+	return enrollment, nil
+}
+
+// moveStudent puts student in class, as a write to it when it is in another one. The caller
+// holds the students lock.
+func (e *EnrollmentRepo) moveStudent(student models.Student, class models.Class) bool {
+	if student.ClassID == class.ID {
+		return false
+	}
+	student.ClassID, student.Class = class.ID, class.Name
+	stamp(&student, version(student)+1)
+	e.students.rows[student.ID] = student
+	return true
+}
+
+// enrolledDuring reports whether the student with studentID has an enrollment on a day from
+// start to end. The caller holds the enrollments lock.
+func (e *EnrollmentRepo) enrolledDuring(studentID int, start, end models.Date) bool {
+	for _, enrollment := range e.enrollments.rows {
+		if enrollment.StudentID == studentID && !enrollment.StartDate.After(end.Time) && !enrollment.EndDate.Before(start.Time) {
+			return true
+		}
+	}
+	return false
+}
+
+// insert adds enrollment with the next id. The caller holds the enrollments lock.
+func (e *EnrollmentRepo) insert(enrollment models.Enrollment) models.Enrollment {
+	enrollment.ID = e.enrollments.nextID
+	e.enrollments.rows[enrollment.ID] = enrollment
+	e.enrollments.nextID++
+	return enrollment
+}
+
+// move mirrors moveEnrollment of the SQL version. The caller holds the students lock.
+func (e *EnrollmentRepo) move(studentID, classID int, day models.Date) {
+	e.enrollments.mu.Lock()
+	defer e.enrollments.mu.Unlock()
+
+	moved := models.Enrollment{StudentID: studentID, ClassID: classID, StartDate: day}
+	current, ok := e.enrolledOn(studentID, day)
+	switch {
+	case ok && current.ClassID == classID:
+		return
+	case ok && current.StartDate.Equal(day.Time):
+		current.ClassID = classID
+		e.enrollments.rows[current.ID] = current
+		return
+	case ok:
+		moved.TermID, moved.EndDate = current.TermID, current.EndDate
+		current.EndDate = models.Date{Time: day.AddDate(0, 0, -1)}
+		e.enrollments.rows[current.ID] = current
+	default:
+		term, ok := e.termOn(day)
+		if !ok {
+			return
+		}
+		moved.TermID, moved.EndDate = term.ID, term.EndDate
+		for _, enrollment := range e.enrollments.rows {
+			if enrollment.StudentID == studentID && enrollment.StartDate.After(day.Time) && !enrollment.StartDate.After(moved.EndDate.Time) {
+				moved.EndDate = models.Date{Time: enrollment.StartDate.AddDate(0, 0, -1)}
+			}
+		}
+	}
+	e.insert(moved)
+}
+
+// enrolledOn returns the enrollment of the student with studentID on day. The caller holds the
+// enrollments lock.
+func (e *EnrollmentRepo) enrolledOn(studentID int, day models.Date) (models.Enrollment, bool) {
+	for _, enrollment := range e.enrollments.rows {
+		if enrollment.StudentID == studentID && !enrollment.StartDate.After(day.Time) && !enrollment.EndDate.Before(day.Time) {
+			return enrollment, true
+		}
+	}
+	return models.Enrollment{}, false
+}
+
+// termOn returns the term day lies in, the one that starts first when terms overlap
+func (e *EnrollmentRepo) termOn(day models.Date) (models.Term, bool) {
+	e.terms.mu.RLock()
+	defer e.terms.mu.RUnlock()
+
+	var found models.Term
+	for _, term := range e.terms.rows {
+		if term.StartDate.After(day.Time) || term.EndDate.Before(day.Time) {
+			continue
+		}
+		if found.ID == 0 || term.StartDate.Before(found.StartDate.Time) {
+			found = term
+		}
+	}
+	return found, found.ID != 0
+}
+
+// Promote reads the capacity of class to again, then holds the locks of both tables while it
+// enrolls the students
+func (e *EnrollmentRepo) Promote(ctx context.Context, fromClassID int, to models.Class, term models.Term) ([]models.Enrollment, error) {
+	to, err := e.classes.Get(ctx, to.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.students.mu.Lock()
+	defer e.students.mu.Unlock()
+	e.enrollments.mu.Lock()
+	defer e.enrollments.mu.Unlock()
+
+	var promoted []models.Student
+	for id := range e.students.rows {
+		if student, ok := e.students.live(id); ok && student.ClassID == fromClassID {
+			promoted = append(promoted, student)
+		}
+	}
+	sort.Slice(promoted, func(i, j int) bool { return promoted[i].ID < promoted[j].ID })
+
+	var enrolled []string
+	for _, student := range promoted {
+		if e.enrolledDuring(student.ID, term.StartDate, term.EndDate) {
+			enrolled = append(enrolled, strconv.Itoa(student.ID))
+		}
+	}
+	if len(enrolled) > 0 {
+		return nil, utils.Conflict(fmt.Sprintf("students %s are already enrolled during term %d", strings.Join(enrolled, ", "), term.ID))
+	}
+
+	if len(promoted) == 0 {
+		return []models.Enrollment{}, nil
+	}
+
+	enrolledIn := make(map[int]bool)
+	for _, enrollment := range e.enrollments.rows {
+		if enrollment.ClassID == to.ID && !enrollment.StartDate.After(term.EndDate.Time) && !enrollment.EndDate.Before(term.StartDate.Time) {
+			enrolledIn[enrollment.StudentID] = true
+		}
+	}
+	if to.Capacity != nil && len(enrolledIn)+len(promoted) > *to.Capacity {
+		return nil, utils.Conflict(fmt.Sprintf("class %s has room for %d more students during term %d, not %d", to.Name, max(*to.Capacity-len(enrolledIn), 0), term.ID, len(promoted)))
+	}
+
+	enrollments := make([]models.Enrollment, len(promoted))
+	for i, student := range promoted {
+		enrollments[i] = e.insert(models.Enrollment{StudentID: student.ID, TermID: term.ID, ClassID: to.ID, StartDate: term.StartDate, EndDate: term.EndDate})
+	}
+	return enrollments, nil
+}
+
+// ApplyOn reads the classes before it locks the students, then the enrollments
+func (e *EnrollmentRepo) ApplyOn(ctx context.Context, day models.Date) (int, error) {
+	classes, err := e.classes.List(ctx, repositories.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[int]models.Class, len(classes))
+	for _, class := range classes {
+		byID[class.ID] = class
+	}
+
+	e.students.mu.Lock()
+	defer e.students.mu.Unlock()
+	e.enrollments.mu.Lock()
+	defer e.enrollments.mu.Unlock()
+
+	moved := 0
+	for id := range e.students.rows {
+		student, ok := e.students.live(id)
+		if !ok {
+			continue
+		}
+		enrollment, ok := e.enrolledOn(id, day)
+		if ok && e.moveStudent(student, byID[enrollment.ClassID]) {
+			moved++
+		}
+	}
+	return moved, nil
+}
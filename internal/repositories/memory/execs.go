@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"time"
+)
+
+// ExecRepo is an in-memory repositories.ExecRepository. It stores the password hashes and reset
+// tokens like the execs table does, and strips them from what it returns.
+type ExecRepo struct {
+	execs *table[models.Exec]
+}
+
+var _ repositories.ExecRepository = (*ExecRepo)(nil)
+
+// NewExecRepo stores execs as they are given, with their ids and password hashes
+func NewExecRepo(execs ...models.Exec) *ExecRepo {
+	e := &ExecRepo{
+		execs: newTable(
+			func(e models.Exec) int { return e.ID },
+			func(e *models.Exec, id int) { e.ID = id },
+			"email", "username",
+		),
+	}
+	for _, exec := range execs {
+		e.execs.rows[exec.ID] = exec
+		e.execs.nextID = max(e.execs.nextID, exec.ID+1)
+	}
+	return e
+}
+
+// Exec returns the exec with id as it is stored, for tests to look at its password
+func (e *ExecRepo) Exec(id int) (models.Exec, bool) {
+	e.execs.mu.RLock()
+	defer e.execs.mu.RUnlock()
+	exec, ok := e.execs.rows[id]
+	return exec, ok
+}
+
+// public drops the fields of exec that are never sent to clients
+func public(exec models.Exec) models.Exec {
+	exec.Password = ""
+	exec.PasswordResetToken, exec.PasswordTokenExpires = nil, nil
+	return exec
+}
+
+func (e *ExecRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Exec, error) {
+	execs, err := e.execs.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range execs {
+		execs[i] = public(execs[i])
+	}
+	return execs, nil
+}
+
+func (e *ExecRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	return e.execs.Count(ctx, opts)
+}
+
+func (e *ExecRepo) Get(ctx context.Context, id int) (models.Exec, error) {
+	exec, err := e.execs.Get(ctx, id, false)
+	return public(exec), err
+}
+
+func (e *ExecRepo) GetByUsername(ctx context.Context, username string) (models.Exec, error) {
+	e.execs.mu.RLock()
+	defer e.execs.mu.RUnlock()
+
+	for _, exec := range e.execs.rows {
+		if exec.Username == username {
+			return exec, nil
+		}
+	}
+	return models.Exec{}, repositories.ErrNotFound
+}
+
+func (e *ExecRepo) Create(ctx context.Context, newExecs []models.Exec) ([]models.Exec, error) {
+	hashed := make([]models.Exec, len(newExecs))
+	for i, exec := range newExecs {
+		hashedPassword, err := utils.HashPassword(exec.Password)
+		if err != nil {
+			return nil, err
+		}
+		createdAt := time.Now()
+		exec.Password, exec.UserCreatedAt = hashedPassword, &createdAt
+		hashed[i] = exec
+	}
+
+	added, err := e.execs.Create(ctx, hashed)
+	if err != nil {
+		return nil, err
+	}
+	for i := range added {
+		added[i] = public(added[i])
+	}
+	return added, nil
+}
+
+func (e *ExecRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Exec, error) {
+	updated, err := e.execs.Patch(ctx, patches)
+	if err != nil {
+		return nil, err
+	}
+	for i := range updated {
+		updated[i] = public(updated[i])
+	}
+	return updated, nil
+}
+
+// Delete removes the exec for good, execs are not soft deleted
+func (e *ExecRepo) Delete(ctx context.Context, id int) error {
+	e.execs.mu.Lock()
+	defer e.execs.mu.Unlock()
+
+	if _, ok := e.execs.rows[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(e.execs.rows, id)
+	return nil
+}
+
+func (e *ExecRepo) BulkDelete(ctx context.Context, ids []int) ([]int, error) {
+	e.execs.mu.Lock()
+	defer e.execs.mu.Unlock()
+
+	// a repeated id is already deleted the second time, like in the SQL version
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := e.execs.rows[id]; !ok || seen[id] {
+			return nil, fmt.Errorf("%w: ID %v does not exist", repositories.ErrNotFound, id)
+		}
+		seen[id] = true
+	}
+
+	deletedIds := []int{}
+	for _, id := range ids {
+		delete(e.execs.rows, id)
+		deletedIds = append(deletedIds, id)
+	}
+	return deletedIds, nil
+}
+
+func (e *ExecRepo) SaveResetToken(ctx context.Context, email, hashedToken string, expiry time.Time) (*models.Exec, error) {
+	e.execs.mu.Lock()
+	defer e.execs.mu.Unlock()
+
+	for id, exec := range e.execs.rows {
+		if exec.Email != email {
+			continue
+		}
+		exec.PasswordResetToken, exec.PasswordTokenExpires = &hashedToken, &expiry
+		e.execs.rows[id] = exec
+		return &models.Exec{ID: exec.ID, Email: exec.Email, Username: exec.Username}, nil
+	}
+	return nil, nil
+}
+
+func (e *ExecRepo) ResetPassword(ctx context.Context, hashedToken, newPassword string) error {
+	e.execs.mu.Lock()
+	defer e.execs.mu.Unlock()
+
+	for id, exec := range e.execs.rows {
+		if exec.PasswordResetToken == nil || *exec.PasswordResetToken != hashedToken {
+			continue
+		}
+		if exec.PasswordTokenExpires == nil || time.Now().After(*exec.PasswordTokenExpires) {
+			break
+		}
+
+		hashedPassword, err := utils.HashPassword(newPassword)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		exec.Password, exec.PasswordChangedAt = hashedPassword, &now
+		exec.PasswordResetToken, exec.PasswordTokenExpires = nil, nil
+		e.execs.rows[id] = exec
+		return nil
+	}
+	return utils.Validation("invalid or expired reset code")
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"sort"
+	"strings"
+)
+
+// SearchRepo searches the in-memory teacher and student tables. It stands in for FULLTEXT with a
+// LIKE style substring match, scoring each row by how many of the query's terms it contains.
+type SearchRepo struct {
+	teachers *TeacherRepo
+	students *StudentRepo
+}
+
+var _ repositories.SearchRepository = (*SearchRepo)(nil)
+
+func NewSearchRepo(teachers *TeacherRepo, students *StudentRepo) *SearchRepo {
+	return &SearchRepo{teachers: teachers, students: students}
+}
+
+// score counts the terms found in any of the row's text columns
+func score(row interface{}, columns []string, terms []string) float64 {
+	var text strings.Builder
+	for _, column := range columns {
+		text.WriteString(strings.ToLower(utils.GetColumnValue(row, column)))
+		text.WriteString(" ")
+	}
+
+	var found float64
+	for _, term := range terms {
+		if strings.Contains(text.String(), term) {
+			found++
+		}
+	}
+	return found
+}
+
+func (s *SearchRepo) Search(ctx context.Context, query string, page, limit int) ([]models.SearchResult, int, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	results := make([]models.SearchResult, 0)
+	if len(terms) == 0 {
+		return results, 0, nil
+	}
+
+	teachers, err := s.teachers.List(ctx, repositories.ListOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, teacher := range teachers {
+		if found := score(teacher, []string{"first_name", "last_name", "email", "class", "subject"}, terms); found > 0 {
+			results = append(results, models.SearchResult{Type: models.SearchTypeTeacher, ID: teacher.ID, Score: found, Data: teacher})
+		}
+	}
+
+	students, err := s.students.List(ctx, repositories.ListOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, student := range students {
+		if found := score(student, []string{"first_name", "last_name", "email", "class"}, terms); found > 0 {
+			results = append(results, models.SearchResult{Type: models.SearchTypeStudent, ID: student.ID, Score: found, Data: student})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	total := len(results)
+	if limit > 0 {
+		offset := max(page-1, 0) * limit
+		if offset >= total {
+			return []models.SearchResult{}, total, nil
+		}
+		results = results[offset:min(offset+limit, total)]
+	}
+	return results, total, nil
+}
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+)
+
+// StudentRepo is an in-memory repositories.StudentRepository for tests and local runs without MariaDB
+type StudentRepo struct {
+	*table[models.Student]
+}
+
+var _ repositories.StudentRepository = (*StudentRepo)(nil)
+
+func NewStudentRepo() *StudentRepo {
+	return &StudentRepo{
+		table: newTable(
+			func(s models.Student) int { return s.ID },
+			func(s *models.Student, id int) { s.ID = id },
+			"email",
+		),
+	}
+}
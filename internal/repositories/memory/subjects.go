@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SubjectRepo is an in-memory repositories.SubjectRepository. It keeps the assignments to the
+// teachers of the teacher table it is given, lets those teachers be filtered on them, and assigns
+// every teacher written to the table its subject.
+type SubjectRepo struct {
+	subjects *table[models.Subject]
+	teachers *TeacherRepo
+
+	mu       sync.RWMutex
+	assigned map[int][]int // subject ids by teacher id
+}
+
+var _ repositories.SubjectRepository = (*SubjectRepo)(nil)
+
+func NewSubjectRepo(teachers *TeacherRepo) *SubjectRepo {
+	s := &SubjectRepo{
+		subjects: newTable(
+			func(s models.Subject) int { return s.ID },
+			func(s *models.Subject, id int) { s.ID = id },
+			"name",
+		),
+		teachers: teachers,
+		assigned: make(map[int][]int),
+	}
+	teachers.values["subject"] = s.names
+	// the teachers lock is held, the hooks lock the subjects, then the assignments
+	teachers.created = func(teacher models.Teacher) {
+		s.assignSubject(teacher.ID, "", teacher.Subject)
+	}
+	teachers.updated = func(before, after models.Teacher) {
+		s.assignSubject(after.ID, before.Subject, after.Subject)
+	}
+	return s
+}
+
+// assignSubject mirrors assignSubject of the SQL version
+func (s *SubjectRepo) assignSubject(teacherID int, previous, name string) {
+	s.subjects.mu.Lock()
+	subjectID, previousID := 0, 0
+	for id, subject := range s.subjects.rows {
+		if strings.EqualFold(subject.Name, name) {
+			subjectID = id
+		}
+		if previous != "" && strings.EqualFold(subject.Name, previous) {
+			previousID = id
+		}
+	}
+	if subjectID == 0 {
+		subjectID = s.subjects.nextID
+		s.subjects.rows[subjectID] = models.Subject{ID: subjectID, Name: name}
+		s.subjects.nextID++
+	}
+	s.subjects.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assigned := slices.DeleteFunc(slices.Clone(s.assigned[teacherID]), func(id int) bool { return id == previousID })
+	if !slices.Contains(assigned, subjectID) {
+		assigned = append(assigned, subjectID)
+	}
+	s.assigned[teacherID] = assigned
+}
+
+// names returns the names of the subjects assigned to teacher
+func (s *SubjectRepo) names(teacher models.Teacher) []string {
+	subjects, _ := s.ForTeacher(context.Background(), teacher.ID)
+	names := make([]string, len(subjects))
+	for i, subject := range subjects {
+		names[i] = subject.Name
+	}
+	return names
+}
+
+func (s *SubjectRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Subject, error) {
+	return s.subjects.List(ctx, opts)
+}
+
+func (s *SubjectRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	return s.subjects.Count(ctx, opts)
+}
+
+func (s *SubjectRepo) Get(ctx context.Context, id int, fields ...string) (models.Subject, error) {
+	return s.subjects.Get(ctx, id, false, fields...)
+}
+
+func (s *SubjectRepo) Create(ctx context.Context, subjects []models.Subject) ([]models.Subject, error) {
+	return s.subjects.Create(ctx, subjects)
+}
+
+func (s *SubjectRepo) Update(ctx context.Context, subject models.Subject) (models.Subject, error) {
+	return s.subjects.Update(ctx, subject)
+}
+
+// Delete refuses a subject assigned to a teacher that is still in the teacher table, deleted
+// ones included, like the foreign key does
+func (s *SubjectRepo) Delete(ctx context.Context, id int) error {
+	s.mu.RLock()
+	var teacherIDs []int
+	for teacherID, subjectIDs := range s.assigned {
+		if slices.Contains(subjectIDs, id) {
+			teacherIDs = append(teacherIDs, teacherID)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, teacherID := range teacherIDs {
+		if _, err := s.teachers.Get(ctx, teacherID, true); err == nil {
+			return fmt.Errorf("%w: subject %d is assigned to teachers, counting deleted ones", repositories.ErrInUse, id)
+		}
+	}
+
+	s.subjects.mu.Lock()
+	defer s.subjects.mu.Unlock()
+
+	if _, ok := s.subjects.rows[id]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(s.subjects.rows, id)
+	return nil
+}
+
+func (s *SubjectRepo) ForTeacher(ctx context.Context, teacherID int) ([]models.Subject, error) {
+	s.mu.RLock()
+	subjectIDs := s.assigned[teacherID]
+	s.mu.RUnlock()
+
+	s.subjects.mu.RLock()
+	defer s.subjects.mu.RUnlock()
+
+	subjects := make([]models.Subject, 0, len(subjectIDs))
+	for _, id := range subjectIDs {
+		if subject, ok := s.subjects.rows[id]; ok {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Slice(subjects, func(i, j int) bool { return compareText(subjects[i].Name, subjects[j].Name) < 0 })
+	return subjects, nil
+}
+
+// Assign holds the teachers lock while it replaces the assignments, like the SQL version locks
+// the teacher row
+func (s *SubjectRepo) Assign(ctx context.Context, teacherID int, subjectIDs []int) ([]models.Subject, error) {
+	var unique []models.Subject
+	for _, id := range subjectIDs {
+		subject, err := s.subjects.Get(ctx, id, false)
+		if err != nil {
+			return nil, utils.ValidationFailed([]utils.FieldError{{Field: "subject_id", Rule: "exists", Message: "does not refer to an existing record"}})
+		}
+		if !slices.Contains(unique, subject) {
+			unique = append(unique, subject)
+		}
+	}
+
+	s.teachers.mu.Lock()
+	defer s.teachers.mu.Unlock()
+
+	teacher, ok := s.teachers.live(teacherID)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+
+	s.mu.Lock()
+	s.assigned[teacherID] = nil
+	keeps := false
+	for _, subject := range unique {
+		s.assigned[teacherID] = append(s.assigned[teacherID], subject.ID)
+		keeps = keeps || strings.EqualFold(subject.Name, teacher.Subject)
+	}
+	s.mu.Unlock()
+
+	// the subject of the teacher stays one of its subjects, the first of subjectIDs when it was left out
+	if !keeps {
+		teacher.Subject = unique[0].Name
+		stamp(&teacher, version(teacher)+1)
+		s.teachers.rows[teacherID] = teacher
+	}
+	return s.ForTeacher(ctx, teacherID)
+}
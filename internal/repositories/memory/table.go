@@ -0,0 +1,513 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// table is an in-memory stand-in for a MySQL table of T, keyed by the auto increment id.
+// Filtering and sorting work on the struct's db tags so they match the SQL column names.
+// Columns in unique behave like a unique index over the live rows and are compared case
+// insensitively.
+// A Version field is set to 1 on insert and bumped on every update, which also sets UpdatedAt.
+// Rows with a DeletedAt are soft deleted and stay in rows until they are purged.
+type table[T any] struct {
+	mu     sync.RWMutex
+	rows   map[int]T
+	nextID int
+	getID  func(T) int
+	setID  func(*T, int)
+	unique []string
+	// sameKey, when set, stands in for a unique index over several columns, reporting a row
+	// that shares it with another as a conflict with duplicate
+	sameKey   func(a, b T) bool
+	duplicate string
+	// values holds the values of a column that are kept outside of the row, which a filter on
+	// it matches instead of the column, like the subjects assigned to a teacher
+	values map[string]func(T) []string
+	// created, when set, runs with the lock held for every row Create added
+	created func(row T)
+	// updated, when set, runs with the lock held for every row Update or Patch changed, with the
+	// row as it was before
+	updated func(before, after T)
+}
+
+func newTable[T any](getID func(T) int, setID func(*T, int), unique ...string) *table[T] {
+	return &table[T]{
+		rows:   make(map[int]T),
+		nextID: 1,
+		getID:  getID,
+		setID:  setID,
+		unique: unique,
+		values: make(map[string]func(T) []string),
+	}
+}
+
+// version reads the version column of row, which is 0 for rows without one
+func version[T any](row T) int {
+	return utils.GetColumnInt(row, "version")
+}
+
+// stamp sets the Version and UpdatedAt fields of row, when it has them, for a write now.
+// Only live rows are written, so it also clears DeletedAt.
+func stamp[T any](row *T, version int) {
+	value := reflect.ValueOf(row).Elem()
+	if field := value.FieldByName("Version"); field.IsValid() {
+		field.SetInt(int64(version))
+	}
+	if field := value.FieldByName("UpdatedAt"); field.IsValid() {
+		field.Set(reflect.ValueOf(repositories.Timestamp()))
+	}
+	setDeletedAt(row, nil)
+}
+
+// deletedAt returns when row was soft deleted, nil for live rows
+func deletedAt[T any](row T) *time.Time {
+	field := reflect.ValueOf(row).FieldByName("DeletedAt")
+	if !field.IsValid() {
+		return nil
+	}
+	return field.Interface().(*time.Time)
+}
+
+func setDeletedAt[T any](row *T, at *time.Time) {
+	if field := reflect.ValueOf(row).Elem().FieldByName("DeletedAt"); field.IsValid() {
+		field.Set(reflect.ValueOf(at))
+	}
+}
+
+// live returns the row with id unless it is missing or soft deleted. The caller holds the lock.
+func (t *table[T]) live(id int) (T, bool) {
+	row, ok := t.rows[id]
+	if !ok || deletedAt(row) != nil {
+		var zero T
+		return zero, false
+	}
+	return row, true
+}
+
+// findConflict returns a conflict error when another live row of rows shares a unique column value
+// with row. Soft deleted rows are left out like they are from the live_email indexes.
+func (t *table[T]) findConflict(row T, rows map[int]T) error {
+	for _, column := range t.unique {
+		value := utils.GetColumnValue(row, column)
+		for id, other := range rows {
+			if id != t.getID(row) && deletedAt(other) == nil && strings.EqualFold(utils.GetColumnValue(other, column), value) {
+				return repositories.ConflictError(column, value, id)
+			}
+		}
+	}
+	if t.sameKey != nil {
+		for id, other := range rows {
+			if id != t.getID(row) && t.sameKey(row, other) {
+				return utils.Conflict(t.duplicate)
+			}
+		}
+	}
+	return nil
+}
+
+// compareText compares the way MySQL's default collation does, case insensitively
+func compareText(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareColumn compares two values of column of model the way MySQL compares the column's type:
+// integers numerically, text with compareText, and times and dates by their formatted value,
+// which sorts in time order. NULL, the empty value, sorts first.
+func compareColumn(model interface{}, column, a, b string) int {
+	switch columnKind(model, column) {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, errA := strconv.Atoi(a)
+		y, errB := strconv.Atoi(b)
+		if errA == nil && errB == nil {
+			return x - y
+		}
+	case reflect.String:
+		return compareText(a, b)
+	}
+	if a == "" || b == "" {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// columnKind is the kind of the field of model tagged with column, looking through pointers.
+// It is reflect.Invalid for a column model does not have.
+func columnKind(model interface{}, column string) reflect.Kind {
+	modelType := reflect.TypeOf(model)
+	for i := 0; i < modelType.NumField(); i++ {
+		if strings.TrimSuffix(modelType.Field(i).Tag.Get("db"), ",omitempty") == column {
+			fieldType := modelType.Field(i).Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			return fieldType.Kind()
+		}
+	}
+	return reflect.Invalid
+}
+
+// matchesFilter mirrors utils.AddFilters on a column of model. A null filter is matched by matches, with utils.IsNullColumn.
+func matchesFilter(model interface{}, value string, filter utils.Filter) bool {
+	compare := func(a, b string) int { return compareColumn(model, filter.Field, a, b) }
+	switch filter.Operator {
+	case utils.OpNe:
+		return compare(value, filter.Value) != 0
+	case utils.OpLike:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(filter.Value))
+	case utils.OpIn:
+		for _, v := range filter.Values {
+			if compare(value, v) == 0 {
+				return true
+			}
+		}
+		return false
+	case utils.OpGt:
+		return compare(value, filter.Value) > 0
+	case utils.OpGte:
+		return compare(value, filter.Value) >= 0
+	case utils.OpLt:
+		return compare(value, filter.Value) < 0
+	case utils.OpLte:
+		return compare(value, filter.Value) <= 0
+	default:
+		return compare(value, filter.Value) == 0
+	}
+}
+
+// matching returns the rows that pass every filter of opts, unsorted. The caller holds the lock.
+func (t *table[T]) matching(opts repositories.ListOptions) []T {
+	list := make([]T, 0, len(t.rows))
+	for _, row := range t.rows {
+		if deletedAt(row) != nil && !opts.IncludeDeleted {
+			continue
+		}
+		matches := true
+		for _, filter := range opts.Filters {
+			if !t.matches(row, filter) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			list = append(list, row)
+		}
+	}
+	return list
+}
+
+// matches reports whether filter holds for row. On a column with values kept outside of the row
+// a filter holds when it matches one of them, and a negative filter when its positive one
+// matches none of them.
+func (t *table[T]) matches(row T, filter utils.Filter) bool {
+	values := t.values[filter.Field]
+	if values == nil {
+		if filter.Operator == utils.OpNull {
+			return utils.IsNullColumn(row, filter.Field) == (filter.Value == "true")
+		}
+		return matchesFilter(row, utils.GetColumnValue(row, filter.Field), filter)
+	}
+
+	if positive, negated := utils.Positive(filter); negated {
+		return !t.matches(row, positive)
+	}
+	if filter.Operator == utils.OpNull {
+		return len(values(row)) > 0
+	}
+	for _, value := range values(row) {
+		if matchesFilter(row, value, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *table[T]) List(ctx context.Context, opts repositories.ListOptions) ([]T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	list := t.matching(opts)
+
+	var model T
+	sort.Slice(list, func(i, j int) bool {
+		return compareKeys(model, opts.Sort, utils.NewCursor(opts.Sort, list[i]), utils.NewCursor(opts.Sort, list[j])) < 0
+	})
+
+	if opts.After != nil {
+		// skip the rows up to and including the cursor's row
+		start := sort.Search(len(list), func(i int) bool {
+			return compareKeys(model, opts.Sort, utils.NewCursor(opts.Sort, list[i]), *opts.After) > 0
+		})
+		list = list[start:]
+		if opts.Limit > 0 {
+			list = list[:min(opts.Limit, len(list))]
+		}
+		return list, nil
+	}
+
+	if opts.Limit > 0 {
+		offset := max(opts.Page-1, 0) * opts.Limit
+		if offset >= len(list) {
+			return []T{}, nil
+		}
+		list = list[offset:min(offset+opts.Limit, len(list))]
+	}
+	return list, nil
+}
+
+// compareKeys orders two rows of model by their sort keys, then by id, the way AddSorting orders
+// them in SQL
+func compareKeys(model interface{}, sorts []utils.SortField, a, b utils.Cursor) int {
+	for i, s := range sorts {
+		c := compareColumn(model, s.Field, a.Values[i], b.Values[i])
+		if c == 0 {
+			continue
+		}
+		if s.Order == "desc" {
+			return -c
+		}
+		return c
+	}
+	return a.ID - b.ID
+}
+
+func (t *table[T]) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.matching(opts)), nil
+}
+
+// Get always returns the whole row, the handlers drop the fields that were not asked for
+func (t *table[T]) Get(ctx context.Context, id int, includeDeleted bool, fields ...string) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	row, ok := t.rows[id]
+	if !ok || (deletedAt(row) != nil && !includeDeleted) {
+		var zero T
+		return zero, repositories.ErrNotFound
+	}
+	return row, nil
+}
+
+func (t *table[T]) Create(ctx context.Context, newRows []T) ([]T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// check the whole batch before inserting any of it
+	staged := make(map[int]T, len(t.rows)+len(newRows))
+	for id, row := range t.rows {
+		staged[id] = row
+	}
+	added := make([]T, len(newRows))
+	for i, row := range newRows {
+		t.setID(&row, t.nextID+i)
+		stamp(&row, 1)
+		err := t.findConflict(row, staged)
+		if err != nil {
+			// a conflict with an earlier row of the batch has no existing id, like the rolled back SQL insert
+			var conflict *utils.Error
+			if !errors.As(err, &conflict) {
+				return nil, err
+			}
+			if existingID, ok := conflict.Extensions["existing_id"].(int); ok && existingID >= t.nextID {
+				column := conflict.Extensions["field"].(string)
+				return nil, repositories.ConflictError(column, utils.GetColumnValue(row, column), 0)
+			}
+			return nil, err
+		}
+		staged[t.nextID+i] = row
+		added[i] = row
+	}
+
+	t.rows = staged
+	t.nextID += len(newRows)
+	if t.created != nil {
+		for _, row := range added {
+			t.created(row)
+		}
+	}
+	return added, nil
+}
+
+func (t *table[T]) CreatePartial(ctx context.Context, newRows []T) []repositories.ItemResult[T] {
+	results := make([]repositories.ItemResult[T], len(newRows))
+	for i, row := range newRows {
+		added, err := t.Create(ctx, []T{row})
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Item = added[0]
+	}
+	return results
+}
+
+func (t *table[T]) Update(ctx context.Context, row T) (T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var zero T
+	existing, ok := t.live(t.getID(row))
+	if !ok {
+		return zero, repositories.ErrNotFound
+	}
+	err := repositories.CheckVersion(t.getID(row), version(existing), version(row))
+	if err != nil {
+		return zero, err
+	}
+	err = t.findConflict(row, t.rows)
+	if err != nil {
+		return zero, err
+	}
+	stamp(&row, version(existing)+1)
+	t.rows[t.getID(row)] = row
+	if t.updated != nil {
+		t.updated(existing, row)
+	}
+	return row, nil
+}
+
+func (t *table[T]) Patch(ctx context.Context, patches []repositories.Patch) ([]T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// apply every patch to a copy first so a failing patch leaves the table untouched
+	updated := make([]T, 0, len(patches))
+	for _, patch := range patches {
+		row, ok := t.live(patch.ID)
+		if !ok {
+			return nil, fmt.Errorf("%w: id %d", repositories.ErrNotFound, patch.ID)
+		}
+		err := repositories.CheckVersion(patch.ID, version(row), patch.Version)
+		if err != nil {
+			return nil, err
+		}
+		err = patch.Apply(&row)
+		if err != nil {
+			return nil, err
+		}
+		stamp(&row, version(row)+1)
+		updated = append(updated, row)
+	}
+
+	staged := make(map[int]T, len(t.rows))
+	for id, row := range t.rows {
+		staged[id] = row
+	}
+	for _, row := range updated {
+		staged[t.getID(row)] = row
+	}
+	for _, row := range updated {
+		err := t.findConflict(row, staged)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := t.rows
+	t.rows = staged
+	if t.updated != nil {
+		for _, row := range updated {
+			t.updated(before[t.getID(row)], row)
+		}
+	}
+	return updated, nil
+}
+
+func (t *table[T]) Delete(ctx context.Context, id int, expected int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	row, ok := t.live(id)
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	err := repositories.CheckVersion(id, version(row), expected)
+	if err != nil {
+		return err
+	}
+	t.softDelete(row)
+	return nil
+}
+
+// softDelete marks row as deleted now. The caller holds the lock.
+func (t *table[T]) softDelete(row T) {
+	stamp(&row, version(row)+1)
+	setDeletedAt(&row, repositories.Timestamp())
+	t.rows[t.getID(row)] = row
+}
+
+func (t *table[T]) BulkDelete(ctx context.Context, deletions []repositories.Deletion) ([]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// a repeated id is already deleted the second time, like in the SQL version
+	seen := make(map[int]bool, len(deletions))
+	for _, deletion := range deletions {
+		row, ok := t.live(deletion.ID)
+		if !ok || seen[deletion.ID] {
+			return nil, fmt.Errorf("%w: ID %v does not exist", repositories.ErrNotFound, deletion.ID)
+		}
+		err := repositories.CheckVersion(deletion.ID, version(row), deletion.Version)
+		if err != nil {
+			return nil, err
+		}
+		seen[deletion.ID] = true
+	}
+
+	deletedIds := []int{}
+	for _, deletion := range deletions {
+		row, _ := t.live(deletion.ID)
+		t.softDelete(row)
+		deletedIds = append(deletedIds, deletion.ID)
+	}
+	return deletedIds, nil
+}
+
+func (t *table[T]) Restore(ctx context.Context, id int) (T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var zero T
+	row, ok := t.rows[id]
+	if !ok {
+		return zero, repositories.ErrNotFound
+	}
+	if deletedAt(row) == nil {
+		return zero, repositories.ErrNotDeleted
+	}
+	// a live row may have taken its email in the meantime
+	err := t.findConflict(row, t.rows)
+	if err != nil {
+		return zero, err
+	}
+	stamp(&row, version(row)+1)
+	t.rows[id] = row
+	return row, nil
+}
+
+func (t *table[T]) Purge(ctx context.Context, before time.Time) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	purged := 0
+	for id, row := range t.rows {
+		if at := deletedAt(row); at != nil && at.Before(before) {
+			delete(t.rows, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+)
+
+// TeacherRepo is an in-memory repositories.TeacherRepository for tests and local runs without MariaDB
+type TeacherRepo struct {
+	*table[models.Teacher]
+}
+
+var _ repositories.TeacherRepository = (*TeacherRepo)(nil)
+
+func NewTeacherRepo() *TeacherRepo {
+	return &TeacherRepo{
+		table: newTable(
+			func(t models.Teacher) int { return t.ID },
+			func(t *models.Teacher, id int) { t.ID = id },
+			"email",
+		),
+	}
+}
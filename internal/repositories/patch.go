@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// readOnlyFields are maintained by the repositories and can never be patched
+var readOnlyFields = []string{"id", "version", "updated_at", "deleted_at"}
+
+// JSON Patch operations, RFC 6902
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// PatchOp is one operation of a JSON Patch document. Path and From are JSON Pointers to a
+// top level field, like "/first_name".
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Apply changes model, a pointer to the stored struct, the way the patch describes, runs Prepare
+// and validates the fields it changed. Ops, when set, are a JSON Patch; otherwise Fields is a merge patch.
+func (p Patch) Apply(model interface{}) error {
+	var changed []string
+	var err error
+	if p.Ops != nil {
+		changed, err = ApplyJSONPatch(model, p.Ops)
+	} else {
+		err = ApplyPatch(model, p.Fields)
+		for name := range p.Fields {
+			changed = append(changed, name)
+		}
+	}
+	if err == nil && p.Prepare != nil {
+		err = p.Prepare(model, changed)
+	}
+	if err == nil {
+		err = utils.ValidationFailed(utils.ValidateFields(model, changed))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: id %d", err, p.ID)
+	}
+	return nil
+}
+
+// ApplyPatch merges fields, keyed by json name, into model (a pointer to a struct), like an
+// RFC 7396 merge patch: a null clears the field. The read-only fields are skipped, so a bulk
+// patch can carry the id and version of each record next to its fields.
+func ApplyPatch(model interface{}, fields map[string]interface{}) error {
+	for k, v := range fields {
+		if utils.ContainsString(readOnlyFields, k) {
+			continue // skip the fields the repository maintains
+		}
+
+		fieldVal, ok := fieldByJSONName(model, k)
+		if !ok {
+			return fmt.Errorf("%w: unknown field %s", ErrInvalidPatch, k)
+		}
+		err := setField(fieldVal, k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyJSONPatch applies ops in order to model (a pointer to a struct) and returns the json names
+// of the fields it changed. A failing test op stops the patch with ErrPatchTestFailed.
+func ApplyJSONPatch(model interface{}, ops []PatchOp) ([]string, error) {
+	var changed []string
+	for i, op := range ops {
+		path, err := patchField(model, op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: op %d: %v", ErrInvalidPatch, i, err)
+		}
+		target, _ := fieldByJSONName(model, path)
+
+		value := op.Value
+		switch op.Op {
+		case OpTest:
+			if !jsonEqual(target.Interface(), op.Value) {
+				return nil, fmt.Errorf("%w: op %d: %s is not %v", ErrPatchTestFailed, i, op.Path, op.Value)
+			}
+			continue
+		case OpAdd, OpReplace:
+			// every field of a struct exists, so add replaces like it does for an object member
+		case OpRemove:
+			value = nil
+		case OpMove, OpCopy:
+			from, err := patchField(model, op.From)
+			if err != nil {
+				return nil, fmt.Errorf("%w: op %d: %v", ErrInvalidPatch, i, err)
+			}
+			source, _ := fieldByJSONName(model, from)
+			value = source.Interface()
+			if op.Op == OpMove && from != path {
+				if utils.ContainsString(readOnlyFields, from) {
+					return nil, fmt.Errorf("%w: op %d: %s is read-only", ErrInvalidPatch, i, op.From)
+				}
+				source.Set(reflect.Zero(source.Type()))
+				changed = append(changed, from)
+			}
+		default:
+			return nil, fmt.Errorf("%w: op %d: unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+
+		if utils.ContainsString(readOnlyFields, path) {
+			return nil, fmt.Errorf("%w: op %d: %s is read-only", ErrInvalidPatch, i, op.Path)
+		}
+		err = setField(target, path, value)
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+		changed = append(changed, path)
+	}
+	return changed, nil
+}
+
+// patchField returns the json name of the field a JSON Pointer points to. Only top level
+// fields of model can be addressed.
+func patchField(model interface{}, pointer string) (string, error) {
+	name, ok := strings.CutPrefix(pointer, "/")
+	if !ok || strings.Contains(name, "/") {
+		return "", fmt.Errorf("path %q is not a field", pointer)
+	}
+	name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+	if _, ok := fieldByJSONName(model, name); !ok {
+		return "", fmt.Errorf("unknown field %s", name)
+	}
+	return name, nil
+}
+
+func fieldByJSONName(model interface{}, name string) (reflect.Value, bool) {
+	modelVal := reflect.ValueOf(model).Elem()
+	modelType := modelVal.Type()
+	for i := 0; i < modelVal.NumField(); i++ {
+		if strings.TrimSuffix(modelType.Field(i).Tag.Get("json"), ",omitempty") == name {
+			return modelVal.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setField sets fieldVal to value, or clears it when value is nil
+func setField(fieldVal reflect.Value, name string, value interface{}) error {
+	if value == nil {
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+		return nil
+	}
+	val := reflect.ValueOf(value)
+	// JSON numbers decode as float64, which fits an int field, or a nullable one, when it is whole
+	if number, ok := value.(float64); ok && number == float64(int64(number)) {
+		target := fieldVal.Type()
+		if target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+		if target.Kind() == reflect.Int {
+			converted := reflect.New(target)
+			converted.Elem().SetInt(int64(number))
+			val = converted
+			if fieldVal.Kind() != reflect.Ptr {
+				val = converted.Elem()
+			}
+		}
+	}
+	if !val.Type().AssignableTo(fieldVal.Type()) {
+		return fmt.Errorf("%w: cannot use %v for field %s", ErrInvalidPatch, value, name)
+	}
+	fieldVal.Set(val)
+	return nil
+}
+
+// jsonEqual compares two values the way a test op does, by their JSON
+func jsonEqual(a, b interface{}) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(x) == string(y)
+}
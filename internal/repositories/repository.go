@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/pkg/utils"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a record with the requested id does not exist
+	ErrNotFound = utils.NotFound("record not found")
+	// ErrInvalidPatch is returned when a patch names an unknown field or has a value of the wrong type
+	ErrInvalidPatch = utils.Validation("invalid patch")
+	// ErrPatchTestFailed is returned when a test op of a JSON Patch does not hold for the record
+	ErrPatchTestFailed = utils.Conflict("patch test failed")
+	// ErrInUse is returned when deleting a record other records still refer to
+	ErrInUse = utils.Conflict("record is still in use")
+	// ErrNotDeleted is returned when restoring a record that was never deleted
+	ErrNotDeleted = utils.Conflict("record is not deleted")
+	// ErrVersionMismatch is returned when a write expects a version the record is no longer at
+	ErrVersionMismatch = utils.PreconditionFailed("record has been modified")
+)
+
+// ConflictError reports that a unique field already holds value on the record with existingID.
+// An existingID of 0 means the value is repeated within the same bulk request.
+func ConflictError(field, value string, existingID int) error {
+	if existingID == 0 {
+		return utils.Conflict(fmt.Sprintf("%s %q appears more than once in the request", field, value)).
+			With("field", field)
+	}
+	return utils.Conflict(fmt.Sprintf("%s %q is already in use", field, value)).
+		With("field", field).
+		With("existing_id", existingID)
+}
+
+// CheckVersion fails with ErrVersionMismatch when expected is not 0 and differs from current
+func CheckVersion(id, current, expected int) error {
+	if expected != 0 && expected != current {
+		return fmt.Errorf("%w: id %d is at version %d", ErrVersionMismatch, id, current)
+	}
+	return nil
+}
+
+// Timestamp is the updated_at of a record written now, in whole seconds like the DATETIME column
+func Timestamp() *time.Time {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &now
+}
+
+// ItemResult is the outcome for one item of a bulk request that is allowed to partly succeed
+type ItemResult[T any] struct {
+	Item T
+	Err  error
+}
+
+// ListOptions holds the filtering, sorting and pagination parsed from the query string.
+// A Limit of 0 returns every matching record. When After is set the list starts after
+// that cursor's row instead of at an offset, and Page is ignored.
+// Fields limits the columns read, though id, version, updated_at and the sort columns are always
+// filled in. Soft deleted records are left out unless IncludeDeleted is set.
+type ListOptions struct {
+	Filters        []utils.Filter
+	Sort           []utils.SortField
+	Page           int
+	Limit          int
+	After          *utils.Cursor
+	Fields         []string
+	IncludeDeleted bool
+}
+
+// Patch is a partial update of the record with ID, either a merge patch of Fields keyed by json
+// field name or the Ops of a JSON Patch. A non-zero Version is the version the record must still be at.
+// Prepare, when set, runs on the patched record before it is validated, with the json names of
+// the fields that changed. It can fill in fields derived from them or reject the change.
+type Patch struct {
+	ID      int
+	Version int
+	Fields  map[string]interface{}
+	Ops     []PatchOp
+	Prepare func(model interface{}, changed []string) error
+}
+
+// Deletion names a record of a bulk delete. A non-zero Version is the version the record must still be at.
+type Deletion struct {
+	ID      int
+	Version int
+}
+
+type TeacherRepository interface {
+	// List returns the teachers matching opts. A subject filter matches any subject assigned to a
+	// teacher, while subject[ne] and subject[null]=true hold when none do. Every write of a teacher
+	// assigns it its subject, in place of the one it had, so the subject is one of them.
+	List(ctx context.Context, opts ListOptions) ([]models.Teacher, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	// Get reads the record with id, only filling in fields, id, version and updated_at when fields
+	// are given. A soft deleted record is only found with includeDeleted.
+	Get(ctx context.Context, id int, includeDeleted bool, fields ...string) (models.Teacher, error)
+	// Create adds all teachers or none of them
+	Create(ctx context.Context, teachers []models.Teacher) ([]models.Teacher, error)
+	// CreatePartial adds each teacher on its own, keeping the ones that succeed. The results
+	// are in the same order as teachers.
+	CreatePartial(ctx context.Context, teachers []models.Teacher) []ItemResult[models.Teacher]
+	// Update replaces the record, bumps its version and sets updated_at. A non-zero teacher.Version is the version
+	// the record must still be at.
+	Update(ctx context.Context, teacher models.Teacher) (models.Teacher, error)
+	// Patch applies all patches atomically and returns the updated teachers
+	Patch(ctx context.Context, patches []Patch) ([]models.Teacher, error)
+	// Delete soft deletes the record, which must still be at version unless version is 0.
+	// Update, Patch and Delete treat a soft deleted record as not found.
+	Delete(ctx context.Context, id int, version int) error
+	// BulkDelete soft deletes all records or none of them and returns their ids
+	BulkDelete(ctx context.Context, deletions []Deletion) ([]int, error)
+	// Restore undoes the soft delete of the record with id
+	Restore(ctx context.Context, id int) (models.Teacher, error)
+	// Purge permanently removes the records soft deleted before before and returns how many there were
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+type StudentRepository interface {
+	List(ctx context.Context, opts ListOptions) ([]models.Student, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	// Get reads the record with id, only filling in fields, id, version and updated_at when fields
+	// are given. A soft deleted record is only found with includeDeleted.
+	Get(ctx context.Context, id int, includeDeleted bool, fields ...string) (models.Student, error)
+	// Create adds all students or none of them
+	Create(ctx context.Context, students []models.Student) ([]models.Student, error)
+	// CreatePartial adds each student on its own, keeping the ones that succeed. The results
+	// are in the same order as students.
+	CreatePartial(ctx context.Context, students []models.Student) []ItemResult[models.Student]
+	// Update replaces the record, bumps its version and sets updated_at. A non-zero student.Version is the version
+	// the record must still be at. A new class_id moves the enrollment of the student in the
+	// current term to the class from today, in the same transaction.
+	Update(ctx context.Context, student models.Student) (models.Student, error)
+	// Patch applies all patches atomically and returns the updated students, moving the
+	// enrollment of a student whose class_id changes like Update
+	Patch(ctx context.Context, patches []Patch) ([]models.Student, error)
+	// Delete soft deletes the record, which must still be at version unless version is 0.
+	// Update, Patch and Delete treat a soft deleted record as not found.
+	Delete(ctx context.Context, id int, version int) error
+	// BulkDelete soft deletes all records or none of them and returns their ids
+	BulkDelete(ctx context.Context, deletions []Deletion) ([]int, error)
+	// Restore undoes the soft delete of the record with id
+	Restore(ctx context.Context, id int) (models.Student, error)
+	// Purge permanently removes the records soft deleted before before and returns how many there were
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// SearchRepository ranks teachers and students together for a free text query
+type SearchRepository interface {
+	// Search returns one page of results, best match first, and the total number of matches
+	Search(ctx context.Context, query string, page, limit int) ([]models.SearchResult, int, error)
+}
+
+// ExecRepository stores the execs. The password hash and reset token of an exec never leave
+// it, except through GetByUsername for login.
+type ExecRepository interface {
+	List(ctx context.Context, opts ListOptions) ([]models.Exec, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	Get(ctx context.Context, id int) (models.Exec, error)
+	// GetByUsername reads the exec with username, with its password hash
+	GetByUsername(ctx context.Context, username string) (models.Exec, error)
+	// Create hashes the password of every exec and adds all of them or none. A taken email or
+	// username is a conflict.
+	Create(ctx context.Context, execs []models.Exec) ([]models.Exec, error)
+	// Patch applies all patches atomically and returns the updated execs
+	Patch(ctx context.Context, patches []Patch) ([]models.Exec, error)
+	Delete(ctx context.Context, id int) error
+	// BulkDelete deletes the execs with ids, all of them or none
+	BulkDelete(ctx context.Context, ids []int) ([]int, error)
+	// SaveResetToken stores hashedToken until expiry on the exec with email and returns that
+	// exec, or nil when no exec has the email
+	SaveResetToken(ctx context.Context, email, hashedToken string, expiry time.Time) (*models.Exec, error)
+	// ResetPassword sets newPassword on the exec holding the unexpired hashedToken and clears the
+	// token. It fails validation when no exec holds it.
+	ResetPassword(ctx context.Context, hashedToken, newPassword string) error
+}
+
+type ClassRepository interface {
+	List(ctx context.Context, opts ListOptions) ([]models.Class, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	// Get reads the record with id, only filling in fields and id when fields are given
+	Get(ctx context.Context, id int, fields ...string) (models.Class, error)
+	// GetByName finds a class by its name, case insensitively
+	GetByName(ctx context.Context, name string) (models.Class, error)
+	// Create adds all classes or none of them
+	Create(ctx context.Context, classes []models.Class) ([]models.Class, error)
+	// Update replaces the class. A new name is copied to the class of its teachers and students.
+	Update(ctx context.Context, class models.Class) (models.Class, error)
+	// Patch applies all patches atomically and returns the updated classes, renaming like Update
+	Patch(ctx context.Context, patches []Patch) ([]models.Class, error)
+	// Delete removes the class, failing with ErrInUse while teachers or students are in it or it
+	// has enrollment or attendance history
+	Delete(ctx context.Context, id int) error
+}
+
+type SubjectRepository interface {
+	List(ctx context.Context, opts ListOptions) ([]models.Subject, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	// Get reads the record with id, only filling in fields and id when fields are given
+	Get(ctx context.Context, id int, fields ...string) (models.Subject, error)
+	// Create adds all subjects or none of them
+	Create(ctx context.Context, subjects []models.Subject) ([]models.Subject, error)
+	Update(ctx context.Context, subject models.Subject) (models.Subject, error)
+	// Delete removes the subject, failing with ErrInUse while it is assigned to a teacher
+	Delete(ctx context.Context, id int) error
+	// ForTeacher lists the subjects assigned to the teacher with teacherID, by name
+	ForTeacher(ctx context.Context, teacherID int) ([]models.Subject, error)
+	// Assign replaces the subjects of the teacher with teacherID by subjectIDs, at least one, and
+	// returns them. The first of them becomes the subject of the teacher when it is left out. It
+	// fails with ErrNotFound for a missing or deleted teacher and with a validation error for a
+	// subject that does not exist.
+	Assign(ctx context.Context, teacherID int, subjectIDs []int) ([]models.Subject, error)
+}
+
+// CatalogRepository stores a simple table of reference data, whose records are replaced whole
+// and deleted for good
+type CatalogRepository[T any] interface {
+	List(ctx context.Context, opts ListOptions) ([]T, error)
+	// Count returns the number of records matching the filters of opts, ignoring pagination
+	Count(ctx context.Context, opts ListOptions) (int, error)
+	// Get reads the record with id, only filling in fields and id when fields are given
+	Get(ctx context.Context, id int, fields ...string) (T, error)
+	// Create adds all records or none of them
+	Create(ctx context.Context, records []T) ([]T, error)
+	Update(ctx context.Context, record T) (T, error)
+	// Delete removes the record, failing with ErrInUse while other records refer to it
+	Delete(ctx context.Context, id int) error
+}
+
+type AcademicYearRepository = CatalogRepository[models.AcademicYear]
+
+type TermRepository = CatalogRepository[models.Term]
+
+type EnrollmentRepository interface {
+	// ForStudent lists the enrollments of the student with studentID, oldest first
+	ForStudent(ctx context.Context, studentID int) ([]models.Enrollment, error)
+	// ForTerm lists the enrollments of the term with termID, oldest first
+	ForTerm(ctx context.Context, termID int) ([]models.Enrollment, error)
+	// Create fails with a conflict when the student is already enrolled on one of its days. An
+	// enrollment that covers today also moves the student into its class.
+	Create(ctx context.Context, enrollment models.Enrollment) (models.Enrollment, error)
+	// Promote enrolls every student of the class with fromClassID in class to for term, which
+	// starts after today, all of them or none. The students keep their class until ApplyOn runs
+	// on a day of the term. It fails with a conflict when one of them is already enrolled during
+	// the term, or when class to would then hold more students than its capacity. It returns the
+	// new enrollments.
+	Promote(ctx context.Context, fromClassID int, to models.Class, term models.Term) ([]models.Enrollment, error)
+	// ApplyOn moves every student whose enrollment on day is in another class into that class,
+	// which is how a promotion takes effect when its term starts. It returns how many moved.
+	ApplyOn(ctx context.Context, day models.Date) (int, error)
+}
+
+// AttendanceQuery narrows attendance to a student or a class, from From to To included. Zero
+// values leave that side open.
+type AttendanceQuery struct {
+	StudentID int
+	ClassID   int
+	From      models.Date
+	To        models.Date
+}
+
+type AttendanceRepository interface {
+	// Record stores the roll-call of records, all of it or none, replacing what was recorded for
+	// the same student, class and day. It returns the records as stored.
+	Record(ctx context.Context, records []models.Attendance) ([]models.Attendance, error)
+	// List returns the attendance matching q by date, then class
+	List(ctx context.Context, q AttendanceQuery) ([]models.Attendance, error)
+	// Summarize counts the attendance matching q per student, by student id
+	Summarize(ctx context.Context, q AttendanceQuery) ([]models.AttendanceSummary, error)
+}
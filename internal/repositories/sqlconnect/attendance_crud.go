@@ -0,0 +1,150 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// AttendanceRepo is the MySQL implementation of repositories.AttendanceRepository
+type AttendanceRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.AttendanceRepository = (*AttendanceRepo)(nil)
+
+func NewAttendanceRepo(db *sql.DB) *AttendanceRepo {
+	return &AttendanceRepo{db: db}
+}
+
+const attendanceColumns = "id, student_id, class_id, date, status, note, recorded_by, recorded_at"
+
+func scanAttendance(row interface{ Scan(...interface{}) error }, attendance *models.Attendance) error {
+	return row.Scan(&attendance.ID, &attendance.StudentID, &attendance.ClassID, &attendance.Date, &attendance.Status, &attendance.Note, &attendance.RecordedBy, &attendance.RecordedAt)
+}
+
+// Record upserts all records with one multi-row INSERT and reads them back by their unique key,
+// since LastInsertId says nothing about the rows that were replaced
+func (a *AttendanceRepo) Record(ctx context.Context, records []models.Attendance) ([]models.Attendance, error) {
+	if len(records) == 0 {
+		return []models.Attendance{}, nil
+	}
+
+	now := repositories.Timestamp()
+	var args, keys []interface{}
+	for _, record := range records {
+		record.RecordedAt = now
+		args = append(args, utils.GetStructValues(record)...)
+		keys = append(keys, record.StudentID, record.ClassID, record.Date)
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	query := utils.GenerateBulkInsertQuery("attendance", models.Attendance{}, len(records)) +
+		" ON DUPLICATE KEY UPDATE status = VALUES(status), note = VALUES(note), recorded_by = VALUES(recorded_by), recorded_at = VALUES(recorded_at)"
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, foreignKeyError(err, "error adding data")
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE (student_id, class_id, date) IN ((?, ?, ?)"+strings.Repeat(", (?, ?, ?)", len(records)-1)+") ORDER BY date, class_id, id", keys...)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+	recorded, err := scanAttendanceRows(rows)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+	return recorded, nil
+}
+
+func (a *AttendanceRepo) List(ctx context.Context, q repositories.AttendanceQuery) ([]models.Attendance, error) {
+	where, args := attendanceWhere(q)
+	rows, err := a.db.QueryContext(ctx, "SELECT "+attendanceColumns+" FROM attendance"+where+" ORDER BY date, class_id, id", args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+
+	list, err := scanAttendanceRows(rows)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	return list, nil
+}
+
+func (a *AttendanceRepo) Summarize(ctx context.Context, q repositories.AttendanceQuery) ([]models.AttendanceSummary, error) {
+	where, args := attendanceWhere(q)
+	rows, err := a.db.QueryContext(ctx, "SELECT student_id, status, COUNT(*) FROM attendance"+where+" GROUP BY student_id, status ORDER BY student_id", args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	summaries := make([]models.AttendanceSummary, 0)
+	for rows.Next() {
+		var studentID, days int
+		var status string
+		err := rows.Scan(&studentID, &status, &days)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		if len(summaries) == 0 || summaries[len(summaries)-1].StudentID != studentID {
+			summaries = append(summaries, models.AttendanceSummary{StudentID: studentID})
+		}
+		summaries[len(summaries)-1].Count(status, days)
+	}
+	return summaries, nil
+}
+
+// attendanceWhere builds the WHERE clause of q
+func attendanceWhere(q repositories.AttendanceQuery) (string, []interface{}) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if q.StudentID != 0 {
+		where += " AND student_id = ?"
+		args = append(args, q.StudentID)
+	}
+	if q.ClassID != 0 {
+		where += " AND class_id = ?"
+		args = append(args, q.ClassID)
+	}
+	if !q.From.IsZero() {
+		where += " AND date >= ?"
+		args = append(args, q.From)
+	}
+	if !q.To.IsZero() {
+		where += " AND date <= ?"
+		args = append(args, q.To)
+	}
+	return where, args
+}
+
+// scanAttendanceRows reads and closes rows
+func scanAttendanceRows(rows *sql.Rows) ([]models.Attendance, error) {
+	defer rows.Close()
+
+	list := make([]models.Attendance, 0)
+	for rows.Next() {
+		var attendance models.Attendance
+		err := scanAttendance(rows, &attendance)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, attendance)
+	}
+	return list, rows.Err()
+}
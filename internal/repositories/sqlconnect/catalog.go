@@ -0,0 +1,214 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// catalog is a repositories.CatalogRepository over table, whose columns are the db tags of T.
+// unique is the column a duplicate is reported on. A table whose unique index spans several
+// columns leaves it empty and reports duplicate as the conflict instead.
+type catalog[T any] struct {
+	db        *sql.DB
+	table     string
+	unique    string
+	duplicate string
+	getID     func(T) int
+	setID     func(*T, int)
+}
+
+// conflict translates the error of a write to record, which is nil for a multi-row insert
+func (c *catalog[T]) conflict(ctx context.Context, q queryer, record *T, err error, message string) error {
+	if c.unique == "" {
+		return duplicateError(err, c.duplicate, message)
+	}
+	value := ""
+	if record != nil {
+		value = utils.GetColumnValue(*record, c.unique)
+	}
+	return uniqueConflict(ctx, q, c.table, c.unique, value, err, message)
+}
+
+// AcademicYearRepo is the MySQL implementation of repositories.AcademicYearRepository
+type AcademicYearRepo struct {
+	*catalog[models.AcademicYear]
+}
+
+var _ repositories.AcademicYearRepository = (*AcademicYearRepo)(nil)
+
+func NewAcademicYearRepo(db *sql.DB) *AcademicYearRepo {
+	return &AcademicYearRepo{&catalog[models.AcademicYear]{
+		db:     db,
+		table:  "academic_years",
+		unique: "name",
+		getID:  func(y models.AcademicYear) int { return y.ID },
+		setID:  func(y *models.AcademicYear, id int) { y.ID = id },
+	}}
+}
+
+// TermRepo is the MySQL implementation of repositories.TermRepository
+type TermRepo struct {
+	*catalog[models.Term]
+}
+
+var _ repositories.TermRepository = (*TermRepo)(nil)
+
+func NewTermRepo(db *sql.DB) *TermRepo {
+	return &TermRepo{&catalog[models.Term]{
+		db:        db,
+		table:     "terms",
+		duplicate: "the academic year already has a term with this name",
+		getID:     func(t models.Term) int { return t.ID },
+		setID:     func(t *models.Term, id int) { t.ID = id },
+	}}
+}
+
+func (c *catalog[T]) List(ctx context.Context, opts repositories.ListOptions) ([]T, error) {
+	var model T
+	columns := utils.QueryColumns(model, opts.Fields, opts.Sort)
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + c.table + " WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	list := make([]T, 0)
+	for rows.Next() {
+		var record T
+		err := rows.Scan(utils.ColumnPointers(&record, columns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		list = append(list, record)
+	}
+	return list, nil
+}
+
+func (c *catalog[T]) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := "SELECT COUNT(*) FROM " + c.table + " WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	var total int
+	err := c.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (c *catalog[T]) Get(ctx context.Context, id int, fields ...string) (T, error) {
+	var record, zero T
+	columns := utils.QueryColumns(record, fields, nil)
+	err := c.db.QueryRowContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM "+c.table+" WHERE id = ?", id).Scan(utils.ColumnPointers(&record, columns)...)
+	if err == sql.ErrNoRows {
+		return zero, repositories.ErrNotFound
+	} else if err != nil {
+		return zero, utils.Internal(err, "error retrieving data")
+	}
+	return record, nil
+}
+
+// Create adds all records with one multi-row INSERT, see TeacherRepo.Create for the ids
+func (c *catalog[T]) Create(ctx context.Context, records []T) ([]T, error) {
+	if len(records) == 0 {
+		return []T{}, nil
+	}
+
+	var model T
+	var args []interface{}
+	for _, record := range records {
+		args = append(args, utils.GetStructValues(record)...)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery(c.table, model, len(records)), args...)
+	if err != nil {
+		err = c.conflict(ctx, tx, nil, err, "error adding data")
+		tx.Rollback()
+		return nil, err
+	}
+
+	firstID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	added := make([]T, len(records))
+	for i, record := range records {
+		c.setID(&record, int(firstID)+i)
+		added[i] = record
+	}
+	return added, nil
+}
+
+func (c *catalog[T]) Update(ctx context.Context, record T) (T, error) {
+	var zero T
+	args := append(utils.GetStructValues(record), c.getID(record))
+	res, err := c.db.ExecContext(ctx, utils.GenerateUpdateQuery(c.table, zero), args...)
+	if err != nil {
+		return zero, c.conflict(ctx, c.db, &record, err, "error updating data")
+	}
+
+	// MySQL reports 0 rows for an update that changes nothing, so check that the record exists
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return zero, utils.Internal(err, "error updating data")
+	}
+	if rowsAffected == 0 {
+		return c.Get(ctx, c.getID(record))
+	}
+	return record, nil
+}
+
+func (c *catalog[T]) Delete(ctx context.Context, id int) error {
+	res, err := c.db.ExecContext(ctx, "DELETE FROM "+c.table+" WHERE id = ?", id)
+	if err != nil {
+		err = foreignKeyError(err, "error deleting data")
+		if errors.Is(err, repositories.ErrInUse) {
+			return fmt.Errorf("%w: other records still refer to %s %d", err, c.table, id)
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// ClassRepo is the MySQL implementation of repositories.ClassRepository
+type ClassRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.ClassRepository = (*ClassRepo)(nil)
+
+func NewClassRepo(db *sql.DB) *ClassRepo {
+	return &ClassRepo{db: db}
+}
+
+func scanClass(row interface{ Scan(...interface{}) error }, class *models.Class) error {
+	return row.Scan(&class.ID, &class.Name, &class.GradeLevel, &class.Room, &class.Capacity, &class.HomeroomTeacherID)
+}
+
+func (c *ClassRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Class, error) {
+	columns := utils.QueryColumns(models.Class{}, opts.Fields, opts.Sort)
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM classes WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	classList := make([]models.Class, 0)
+	for rows.Next() {
+		var class models.Class
+		err := rows.Scan(utils.ColumnPointers(&class, columns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		classList = append(classList, class)
+	}
+	return classList, nil
+}
+
+func (c *ClassRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := "SELECT COUNT(*) FROM classes WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	var total int
+	err := c.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (c *ClassRepo) Get(ctx context.Context, id int, fields ...string) (models.Class, error) {
+	var class models.Class
+	columns := utils.QueryColumns(models.Class{}, fields, nil)
+	err := c.db.QueryRowContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM classes WHERE id = ?", id).Scan(utils.ColumnPointers(&class, columns)...)
+	if err == sql.ErrNoRows {
+		return models.Class{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Class{}, utils.Internal(err, "error retrieving data")
+	}
+	return class, nil
+}
+
+// GetByName relies on the case insensitive collation of classes.name
+func (c *ClassRepo) GetByName(ctx context.Context, name string) (models.Class, error) {
+	var class models.Class
+	err := scanClass(c.db.QueryRowContext(ctx, "SELECT id, name, grade_level, room, capacity, homeroom_teacher_id FROM classes WHERE name = ?", name), &class)
+	if err == sql.ErrNoRows {
+		return models.Class{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Class{}, utils.Internal(err, "error retrieving data")
+	}
+	return class, nil
+}
+
+// Create adds all classes with one multi-row INSERT, see TeacherRepo.Create for the ids
+func (c *ClassRepo) Create(ctx context.Context, newClasses []models.Class) ([]models.Class, error) {
+	if len(newClasses) == 0 {
+		return []models.Class{}, nil
+	}
+
+	var args []interface{}
+	for _, newClass := range newClasses {
+		args = append(args, utils.GetStructValues(newClass)...)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery("classes", models.Class{}, len(newClasses)), args...)
+	if err != nil {
+		err = uniqueConflict(ctx, tx, "classes", "name", "", err, "error adding data")
+		tx.Rollback()
+		return nil, err
+	}
+
+	firstID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	addedClasses := make([]models.Class, len(newClasses))
+	for i, newClass := range newClasses {
+		newClass.ID = int(firstID) + i
+		addedClasses[i] = newClass
+	}
+	return addedClasses, nil
+}
+
+func (c *ClassRepo) Update(ctx context.Context, class models.Class) (models.Class, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Class{}, utils.Internal(err, "error updating data")
+	}
+
+	err = updateClass(ctx, tx, class)
+	if err != nil {
+		tx.Rollback()
+		return models.Class{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return models.Class{}, utils.Internal(err, "error updating data")
+	}
+	return class, nil
+}
+
+func (c *ClassRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Class, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	updatedClasses := make([]models.Class, 0, len(patches))
+	for _, patch := range patches {
+		var classFromDb models.Class
+		err := scanClass(tx.QueryRowContext(ctx, "SELECT id, name, grade_level, room, capacity, homeroom_teacher_id FROM classes WHERE id = ? FOR UPDATE", patch.ID), &classFromDb)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("%w: class %d", repositories.ErrNotFound, patch.ID)
+			}
+			return nil, utils.Internal(err, "error updating data")
+		}
+
+		err = patch.Apply(&classFromDb)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = updateClass(ctx, tx, classFromDb)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		updatedClasses = append(updatedClasses, classFromDb)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+	return updatedClasses, nil
+}
+
+// updateClass writes class and copies its name to the teachers and students in it, bumping
+// their version so cached copies of them are revalidated
+func updateClass(ctx context.Context, tx *sql.Tx, class models.Class) error {
+	res, err := tx.ExecContext(ctx, "UPDATE classes SET name = ?, grade_level = ?, room = ?, capacity = ?, homeroom_teacher_id = ? WHERE id = ?", class.Name, class.GradeLevel, class.Room, class.Capacity, class.HomeroomTeacherID, class.ID)
+	if err != nil {
+		return uniqueConflict(ctx, tx, "classes", "name", class.Name, err, "error updating data")
+	}
+
+	// MySQL reports 0 rows for an update that changes nothing, so check that the class exists
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.Internal(err, "error updating data")
+	}
+	if rowsAffected == 0 {
+		var id int
+		err = tx.QueryRowContext(ctx, "SELECT id FROM classes WHERE id = ?", class.ID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return repositories.ErrNotFound
+		} else if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+	}
+
+	now := repositories.Timestamp()
+	for _, table := range []string{"teachers", "students"} {
+		_, err = tx.ExecContext(ctx, "UPDATE "+table+" SET class = ?, version = version + 1, updated_at = ? WHERE class_id = ? AND BINARY class <> ?", class.Name, now, class.ID, class.Name)
+		if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+	}
+	return nil
+}
+
+// Delete counts the members first to say who is still in the class. The foreign keys of
+// teachers, students, enrollments and attendance also stop the delete, the first two when a
+// member is added in between.
+func (c *ClassRepo) Delete(ctx context.Context, id int) error {
+	var teachers, students int
+	err := c.db.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM teachers WHERE class_id = ?), (SELECT COUNT(*) FROM students WHERE class_id = ?)", id, id).Scan(&teachers, &students)
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	if teachers > 0 || students > 0 {
+		return fmt.Errorf("%w: class %d has %d teacher(s) and %d student(s), counting deleted ones", repositories.ErrInUse, id, teachers, students)
+	}
+
+	res, err := c.db.ExecContext(ctx, "DELETE FROM classes WHERE id = ?", id)
+	if err != nil {
+		err = foreignKeyError(err, "error deleting data")
+		if errors.Is(err, repositories.ErrInUse) {
+			return fmt.Errorf("%w: class %d is in the enrollment or attendance history", err, id)
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
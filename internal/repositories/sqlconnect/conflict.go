@@ -0,0 +1,122 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers for the constraints the repositories translate
+const (
+	// erDupEntry is a duplicate value in a unique index
+	erDupEntry = 1062
+	// erRowIsReferenced is a delete or update of a row a foreign key still points to
+	erRowIsReferenced = 1451
+	// erNoReferencedRow is a foreign key pointing to a row that does not exist
+	erNoReferencedRow = 1452
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// duplicateValue reads the value out of "Duplicate entry 'value' for key 'name'"
+var duplicateValue = regexp.MustCompile(`Duplicate entry '(.*)' for key`)
+
+// duplicateKey reads the index out of "... for key 'name'", which MySQL 8 prefixes with the table
+var duplicateKey = regexp.MustCompile(`for key '(?:\w+\.)?(\w+)'`)
+
+// foreignKeyColumn reads the column out of "... FOREIGN KEY (`column`) REFERENCES ..."
+var foreignKeyColumn = regexp.MustCompile("FOREIGN KEY \\(`(\\w+)`\\)")
+
+// emailConflict translates a duplicate email into a typed conflict naming the record that
+// already has it. email is the only unique column of teachers and students besides id, and only
+// among their live rows: the unique index is on live_email, which is NULL once a row is deleted.
+// An empty email is read from the error, for multi-row inserts where the row is unknown.
+// Any other error is reported as internal with message.
+func emailConflict(ctx context.Context, q queryer, table, email string, err error, message string) error {
+	return liveConflict(ctx, q, table, "email", email, "SELECT id FROM "+table+" WHERE email = ? AND deleted_at IS NULL", err, message)
+}
+
+// uniqueConflict is emailConflict for any unique column of table. Errors of a foreign key are
+// translated by foreignKeyError.
+func uniqueConflict(ctx context.Context, q queryer, table, column, value string, err error, message string) error {
+	return liveConflict(ctx, q, table, column, value, "SELECT id FROM "+table+" WHERE "+column+" = ?", err, message)
+}
+
+// liveConflict translates a duplicate value of column, looking up the record holding it with lookup
+func liveConflict(ctx context.Context, q queryer, table, column, value, lookup string, err error, message string) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != erDupEntry {
+		return foreignKeyError(err, message)
+	}
+
+	if value == "" {
+		match := duplicateValue.FindStringSubmatch(mysqlErr.Message)
+		if match == nil {
+			return utils.Internal(err, message)
+		}
+		value = match[1]
+	}
+
+	// the failed statement was rolled back, so a missing row means the value was repeated in the request
+	var existingID int
+	lookupErr := q.QueryRowContext(ctx, lookup, value).Scan(&existingID)
+	if lookupErr != nil && lookupErr != sql.ErrNoRows {
+		return utils.Internal(lookupErr, message)
+	}
+	return repositories.ConflictError(column, value, existingID)
+}
+
+// execConflict translates a duplicate email or username of an exec into a typed conflict. Both
+// unique indexes of execs are named after their column.
+func execConflict(ctx context.Context, q queryer, err error, message string) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != erDupEntry {
+		return foreignKeyError(err, message)
+	}
+	match := duplicateKey.FindStringSubmatch(mysqlErr.Message)
+	if match == nil || (match[1] != "email" && match[1] != "username") {
+		return utils.Internal(err, message)
+	}
+	return uniqueConflict(ctx, q, "execs", match[1], "", err, message)
+}
+
+// duplicateError reports a duplicate in a unique index over several columns as a conflict with
+// detail, where there is no single value to look up. Errors of a foreign key are translated by
+// foreignKeyError.
+func duplicateError(err error, detail, message string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == erDupEntry {
+		return utils.Conflict(detail)
+	}
+	return foreignKeyError(err, message)
+}
+
+// foreignKeyError translates a row that is still referenced into ErrInUse, and a reference to a
+// missing row into a validation error on the referencing column. Any other error is reported as
+// internal with message.
+func foreignKeyError(err error, message string) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return utils.Internal(err, message)
+	}
+
+	switch mysqlErr.Number {
+	case erRowIsReferenced:
+		return repositories.ErrInUse
+	case erNoReferencedRow:
+		column := "id"
+		if match := foreignKeyColumn.FindStringSubmatch(mysqlErr.Message); match != nil {
+			column = match[1]
+		}
+		return utils.ValidationFailed([]utils.FieldError{{Field: column, Rule: "exists", Message: "does not refer to an existing record"}})
+	}
+	return utils.Internal(err, message)
+}
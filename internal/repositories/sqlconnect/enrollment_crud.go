@@ -0,0 +1,300 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// EnrollmentRepo is the MySQL implementation of repositories.EnrollmentRepository
+type EnrollmentRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.EnrollmentRepository = (*EnrollmentRepo)(nil)
+
+func NewEnrollmentRepo(db *sql.DB) *EnrollmentRepo {
+	return &EnrollmentRepo{db: db}
+}
+
+func (e *EnrollmentRepo) ForStudent(ctx context.Context, studentID int) ([]models.Enrollment, error) {
+	rows, err := e.db.QueryContext(ctx, "SELECT id, student_id, term_id, class_id, start_date, end_date FROM enrollments WHERE student_id = ? ORDER BY start_date, id", studentID)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	enrollments := make([]models.Enrollment, 0)
+	for rows.Next() {
+		var enrollment models.Enrollment
+		err := rows.Scan(&enrollment.ID, &enrollment.StudentID, &enrollment.TermID, &enrollment.ClassID, &enrollment.StartDate, &enrollment.EndDate)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, nil
+}
+
+// ForTerm lists the enrollments of the term with termID, deleted students included
+func (e *EnrollmentRepo) ForTerm(ctx context.Context, termID int) ([]models.Enrollment, error) {
+	rows, err := e.db.QueryContext(ctx, "SELECT id, student_id, term_id, class_id, start_date, end_date FROM enrollments WHERE term_id = ? ORDER BY start_date, id", termID)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	enrollments := make([]models.Enrollment, 0)
+	for rows.Next() {
+		var enrollment models.Enrollment
+		err := rows.Scan(&enrollment.ID, &enrollment.StudentID, &enrollment.TermID, &enrollment.ClassID, &enrollment.StartDate, &enrollment.EndDate)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, nil
+}
+
+// Create locks the student so two enrollments of it cannot both pass the overlap check
+func (e *EnrollmentRepo) Create(ctx context.Context, enrollment models.Enrollment) (models.Enrollment, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Enrollment{}, utils.Internal(err, "error adding data")
+	}
+
+	var studentID int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM students WHERE id = ? FOR UPDATE", enrollment.StudentID).Scan(&studentID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return models.Enrollment{}, repositories.ErrNotFound
+	} else if err != nil {
+		tx.Rollback()
+		return models.Enrollment{}, utils.Internal(err, "error adding data")
+	}
+
+	enrolled, err := enrolledDuring(ctx, tx, []int{enrollment.StudentID}, enrollment.StartDate, enrollment.EndDate)
+	if err != nil {
+		tx.Rollback()
+		return models.Enrollment{}, err
+	}
+	if len(enrolled) > 0 {
+		tx.Rollback()
+		return models.Enrollment{}, utils.Conflict(fmt.Sprintf("student %d is already enrolled between %s and %s", enrollment.StudentID, enrollment.StartDate, enrollment.EndDate))
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateInsertQuery("enrollments", models.Enrollment{}), utils.GetStructValues(enrollment)...)
+	if err != nil {
+		tx.Rollback()
+		return models.Enrollment{}, foreignKeyError(err, "error adding data")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.Enrollment{}, utils.Internal(err, "error adding data")
+	}
+
+	today := models.Today()
+	if !enrollment.StartDate.After(today.Time) && !enrollment.EndDate.Before(today.Time) {
+		_, err = tx.ExecContext(ctx, "UPDATE students JOIN classes ON classes.id = ? SET students.class_id = classes.id, students.class = classes.name, students.version = students.version + 1, students.updated_at = ? WHERE students.id = ? AND students.class_id <> classes.id", enrollment.ClassID, repositories.Timestamp(), enrollment.StudentID)
+		if err != nil {
+			tx.Rollback()
+			return models.Enrollment{}, utils.Internal(err, "error adding data")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return models.Enrollment{}, utils.Internal(err, "error adding data")
+	}
+	enrollment.ID = int(id)
+	return enrollment, nil
+}
+
+// Promote locks the students of the class and the class they go to, checks none of the students
+// is enrolled during the term and that they fit in the class, then enrolls them with one
+// multi-row INSERT
+func (e *EnrollmentRepo) Promote(ctx context.Context, fromClassID int, to models.Class, term models.Term) ([]models.Enrollment, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM students WHERE class_id = ? AND deleted_at IS NULL ORDER BY id FOR UPDATE", fromClassID)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error updating data")
+	}
+	var studentIDs []int
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, utils.Internal(err, "error updating data")
+		}
+		studentIDs = append(studentIDs, id)
+	}
+	rows.Close()
+
+	if len(studentIDs) == 0 {
+		tx.Rollback()
+		return []models.Enrollment{}, nil
+	}
+
+	enrolled, err := enrolledDuring(ctx, tx, studentIDs, term.StartDate, term.EndDate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(enrolled) > 0 {
+		tx.Rollback()
+		return nil, utils.Conflict(fmt.Sprintf("students %s are already enrolled during term %d", strings.Join(enrolled, ", "), term.ID))
+	}
+
+	var capacity sql.NullInt64
+	var enrolledIn int
+	err = tx.QueryRowContext(ctx, "SELECT capacity FROM classes WHERE id = ? FOR UPDATE", to.ID).Scan(&capacity)
+	if err == nil {
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(DISTINCT student_id) FROM enrollments WHERE class_id = ? AND start_date <= ? AND end_date >= ?", to.ID, term.EndDate, term.StartDate).Scan(&enrolledIn)
+	}
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, repositories.ErrNotFound
+	} else if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error updating data")
+	}
+	// a class without a capacity has no limit
+	if capacity.Valid && enrolledIn+len(studentIDs) > int(capacity.Int64) {
+		tx.Rollback()
+		return nil, utils.Conflict(fmt.Sprintf("class %s has room for %d more students during term %d, not %d", to.Name, max(int(capacity.Int64)-enrolledIn, 0), term.ID, len(studentIDs)))
+	}
+
+	enrollments := make([]models.Enrollment, len(studentIDs))
+	var args []interface{}
+	for i, studentID := range studentIDs {
+		enrollments[i] = models.Enrollment{StudentID: studentID, TermID: term.ID, ClassID: to.ID, StartDate: term.StartDate, EndDate: term.EndDate}
+		args = append(args, utils.GetStructValues(enrollments[i])...)
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery("enrollments", models.Enrollment{}, len(enrollments)), args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, foreignKeyError(err, "error updating data")
+	}
+
+	firstID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	for i := range enrollments {
+		enrollments[i].ID = int(firstID) + i
+	}
+	return enrollments, nil
+}
+
+// ApplyOn moves the students with one UPDATE over their enrollment of the day
+func (e *EnrollmentRepo) ApplyOn(ctx context.Context, day models.Date) (int, error) {
+	res, err := e.db.ExecContext(ctx, "UPDATE students JOIN enrollments ON enrollments.student_id = students.id AND enrollments.start_date <= ? AND enrollments.end_date >= ? JOIN classes ON classes.id = enrollments.class_id SET students.class_id = classes.id, students.class = classes.name, students.version = students.version + 1, students.updated_at = ? WHERE students.class_id <> classes.id AND students.deleted_at IS NULL", day, day, repositories.Timestamp())
+	if err != nil {
+		return 0, utils.Internal(err, "error updating data")
+	}
+
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, utils.Internal(err, "error updating data")
+	}
+	return int(moved), nil
+}
+
+// enrolledDuring names the students of studentIDs that have an enrollment on a day from start
+// to end
+func enrolledDuring(ctx context.Context, tx *sql.Tx, studentIDs []int, start, end models.Date) ([]string, error) {
+	args := []interface{}{end, start}
+	for _, id := range studentIDs {
+		args = append(args, id)
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT student_id FROM enrollments WHERE start_date <= ? AND end_date >= ? AND student_id IN (?"+strings.Repeat(", ?", len(studentIDs)-1)+") ORDER BY student_id", args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	var enrolled []string
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		enrolled = append(enrolled, id)
+	}
+	return enrolled, rows.Err()
+}
+
+// moveEnrollment records in tx that the student with studentID is in the class with classID from
+// day on, when day lies in a term. The enrollment of the student on day ends the day before, or
+// takes the new class when it starts on day. The new enrollment runs to the end of the one it
+// replaces, or else to the end of the term or up to the next enrollment of the student in it.
+func moveEnrollment(ctx context.Context, tx *sql.Tx, studentID, classID int, day models.Date) error {
+	var current models.Enrollment
+	err := tx.QueryRowContext(ctx, "SELECT id, term_id, class_id, start_date, end_date FROM enrollments WHERE student_id = ? AND start_date <= ? AND end_date >= ? FOR UPDATE", studentID, day, day).
+		Scan(&current.ID, &current.TermID, &current.ClassID, &current.StartDate, &current.EndDate)
+	if err != nil && err != sql.ErrNoRows {
+		return utils.Internal(err, "error updating data")
+	}
+
+	moved := models.Enrollment{StudentID: studentID, ClassID: classID, StartDate: day}
+	switch {
+	case err == nil && current.ClassID == classID:
+		return nil
+	case err == nil && current.StartDate.Equal(day.Time):
+		_, err = tx.ExecContext(ctx, "UPDATE enrollments SET class_id = ? WHERE id = ?", classID, current.ID)
+		if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+		return nil
+	case err == nil:
+		_, err = tx.ExecContext(ctx, "UPDATE enrollments SET end_date = ? WHERE id = ?", models.Date{Time: day.AddDate(0, 0, -1)}, current.ID)
+		if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+		moved.TermID, moved.EndDate = current.TermID, current.EndDate
+	default:
+		err = tx.QueryRowContext(ctx, "SELECT id, end_date FROM terms WHERE start_date <= ? AND end_date >= ? ORDER BY start_date LIMIT 1", day, day).Scan(&moved.TermID, &moved.EndDate)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+
+		var next models.Date
+		err = tx.QueryRowContext(ctx, "SELECT MIN(start_date) FROM enrollments WHERE student_id = ? AND start_date > ? AND start_date <= ?", studentID, day, moved.EndDate).Scan(&next)
+		if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+		if !next.IsZero() {
+			moved.EndDate = models.Date{Time: next.AddDate(0, 0, -1)}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, utils.GenerateInsertQuery("enrollments", models.Enrollment{}), utils.GetStructValues(moved)...)
+	if err != nil {
+		return foreignKeyError(err, "error updating data")
+	}
+	return nil
+}
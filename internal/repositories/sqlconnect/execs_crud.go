@@ -0,0 +1,304 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+	"time"
+)
+
+// execColumns are the columns of execs that are read back to clients. The password and the
+// reset token columns are left out so they can't leak through a list, a sort or a patch.
+var execColumns = []string{"id", "first_name", "last_name", "email", "username", "password_changed_at", "user_created_at", "inactive_status", "role"}
+
+// ExecRepo is the MySQL implementation of repositories.ExecRepository
+type ExecRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.ExecRepository = (*ExecRepo)(nil)
+
+func NewExecRepo(db *sql.DB) *ExecRepo {
+	return &ExecRepo{db: db}
+}
+
+func (e *ExecRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Exec, error) {
+	query := "SELECT " + strings.Join(execColumns, ", ") + " FROM execs WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	execs := make([]models.Exec, 0)
+	for rows.Next() {
+		var exec models.Exec
+		err := rows.Scan(utils.ColumnPointers(&exec, execColumns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		execs = append(execs, exec)
+	}
+	return execs, nil
+}
+
+func (e *ExecRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := "SELECT COUNT(*) FROM execs WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	var total int
+	err := e.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (e *ExecRepo) Get(ctx context.Context, id int) (models.Exec, error) {
+	return getExec(ctx, e.db, id, "")
+}
+
+// getExec reads the exec with id through q, with lock appended to the query, like FOR UPDATE
+func getExec(ctx context.Context, q queryer, id int, lock string) (models.Exec, error) {
+	var exec models.Exec
+	err := q.QueryRowContext(ctx, "SELECT "+strings.Join(execColumns, ", ")+" FROM execs WHERE id = ? "+lock, id).Scan(utils.ColumnPointers(&exec, execColumns)...)
+	if err == sql.ErrNoRows {
+		return models.Exec{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Exec{}, utils.Internal(err, "error retrieving data")
+	}
+	return exec, nil
+}
+
+func (e *ExecRepo) GetByUsername(ctx context.Context, username string) (models.Exec, error) {
+	var user models.Exec
+	err := e.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, username, password, inactive_status, role FROM execs WHERE username = ?", username).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Username, &user.Password, &user.InactiveStatus, &user.Role)
+	if err == sql.ErrNoRows {
+		return models.Exec{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Exec{}, utils.Internal(err, "internal error")
+	}
+	return user, nil
+}
+
+func (e *ExecRepo) Create(ctx context.Context, newExecs []models.Exec) ([]models.Exec, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO execs (first_name, last_name, email, username, password, user_created_at, inactive_status, role) VALUES (?,?,?,?,?,?,?,?)")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+	defer stmt.Close()
+
+	addedExecs := make([]models.Exec, len(newExecs))
+	for i, newExec := range newExecs {
+		hashedPassword, err := utils.HashPassword(newExec.Password)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		createdAt := time.Now()
+		res, err := stmt.ExecContext(ctx, newExec.FirstName, newExec.LastName, newExec.Email, newExec.Username, hashedPassword, createdAt, newExec.InactiveStatus, newExec.Role)
+		if err != nil {
+			err = execConflict(ctx, tx, err, "error adding data")
+			tx.Rollback()
+			return nil, err
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.Internal(err, "error adding data")
+		}
+
+		// never send the password hash back to the client
+		newExec.ID = int(lastID)
+		newExec.Password = ""
+		newExec.UserCreatedAt = &createdAt
+		addedExecs[i] = newExec
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+	return addedExecs, nil
+}
+
+func (e *ExecRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Exec, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	updatedExecs := make([]models.Exec, 0, len(patches))
+	for _, patch := range patches {
+		execFromDb, err := getExec(ctx, tx, patch.ID, "FOR UPDATE")
+		if err != nil {
+			tx.Rollback()
+			if err == repositories.ErrNotFound {
+				return nil, fmt.Errorf("%w: exec %d", repositories.ErrNotFound, patch.ID)
+			}
+			return nil, err
+		}
+
+		err = patch.Apply(&execFromDb)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE execs SET first_name = ?, last_name = ?, email = ?, username = ?, inactive_status = ?, role = ? WHERE id = ?", execFromDb.FirstName, execFromDb.LastName, execFromDb.Email, execFromDb.Username, execFromDb.InactiveStatus, execFromDb.Role, execFromDb.ID)
+		if err != nil {
+			err = execConflict(ctx, tx, err, "error updating data")
+			tx.Rollback()
+			return nil, err
+		}
+		updatedExecs = append(updatedExecs, execFromDb)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+	return updatedExecs, nil
+}
+
+func (e *ExecRepo) Delete(ctx context.Context, id int) error {
+	res, err := e.db.ExecContext(ctx, "DELETE FROM execs WHERE id = ?", id)
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (e *ExecRepo) BulkDelete(ctx context.Context, ids []int) ([]int, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM execs WHERE id = ?")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	defer stmt.Close()
+
+	deletedIds := []int{}
+	for _, id := range ids {
+		res, err := stmt.ExecContext(ctx, id)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.Internal(err, "error deleting data")
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.Internal(err, "error deleting data")
+		}
+
+		if rowsAffected < 1 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: ID %v does not exist", repositories.ErrNotFound, id)
+		}
+		deletedIds = append(deletedIds, id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	return deletedIds, nil
+}
+
+func (e *ExecRepo) SaveResetToken(ctx context.Context, email, hashedToken string, expiry time.Time) (*models.Exec, error) {
+	exec := &models.Exec{}
+	err := e.db.QueryRowContext(ctx, "SELECT id, email, username FROM execs WHERE email = ?", email).Scan(&exec.ID, &exec.Email, &exec.Username)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, utils.Internal(err, "internal error")
+	}
+
+	_, err = e.db.ExecContext(ctx, "UPDATE execs SET password_reset_token = ?, password_token_expires = ? WHERE id = ?", hashedToken, expiry, exec.ID)
+	if err != nil {
+		return nil, utils.Internal(err, "internal error")
+	}
+	return exec, nil
+}
+
+// ResetPassword clears the token in the same transaction so it can only be used once
+func (e *ExecRepo) ResetPassword(ctx context.Context, hashedToken, newPassword string) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.Internal(err, "internal error")
+	}
+
+	var id int
+	var expires *time.Time
+	err = tx.QueryRowContext(ctx, "SELECT id, password_token_expires FROM execs WHERE password_reset_token = ? FOR UPDATE", hashedToken).Scan(&id, &expires)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return utils.Validation("invalid or expired reset code")
+		}
+		return utils.Internal(err, "internal error")
+	}
+
+	if expires == nil || time.Now().After(*expires) {
+		tx.Rollback()
+		return utils.Validation("invalid or expired reset code")
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE execs SET password = ?, password_reset_token = NULL, password_token_expires = NULL, password_changed_at = ? WHERE id = ?", hashedPassword, time.Now(), id)
+	if err != nil {
+		tx.Rollback()
+		return utils.Internal(err, "internal error")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return utils.Internal(err, "internal error")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// SearchRepo is the MySQL implementation of repositories.SearchRepository, backed by the
+// FULLTEXT indexes from migration 004
+type SearchRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.SearchRepository = (*SearchRepo)(nil)
+
+func NewSearchRepo(db *sql.DB) *SearchRepo {
+	return &SearchRepo{db: db}
+}
+
+// searchQuery builds the UNION of matching teachers and students with their score.
+// Class codes like 9A are shorter than innodb_ft_min_token_size and never reach the
+// FULLTEXT index, so every term is also compared against class directly.
+func searchQuery(query string) (string, []interface{}) {
+	terms := strings.Fields(query)
+	classIn := "class IN (?" + strings.Repeat(", ?", len(terms)-1) + ")"
+
+	var args []interface{}
+	selectFor := func(searchType, table, columns, subject string) string {
+		match := "MATCH(" + columns + ") AGAINST (? IN NATURAL LANGUAGE MODE)"
+		args = append(args, query)
+		for _, term := range terms {
+			args = append(args, term)
+		}
+		args = append(args, query)
+		for _, term := range terms {
+			args = append(args, term)
+		}
+		return "SELECT '" + searchType + "' AS type, id, first_name, last_name, email, class, " + subject + " AS subject, " +
+			match + " + IF(" + classIn + ", 1, 0) AS score FROM " + table +
+			" WHERE deleted_at IS NULL AND (" + match + " OR " + classIn + ")"
+	}
+
+	union := selectFor(models.SearchTypeTeacher, "teachers", "first_name, last_name, email, class, subject", "subject") +
+		" UNION ALL " +
+		selectFor(models.SearchTypeStudent, "students", "first_name, last_name, email, class", "''")
+	return union, args
+}
+
+func (s *SearchRepo) Search(ctx context.Context, query string, page, limit int) ([]models.SearchResult, int, error) {
+	if len(strings.Fields(query)) == 0 {
+		return []models.SearchResult{}, 0, nil
+	}
+	union, args := searchQuery(query)
+
+	var total int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+union+") AS results", args...).Scan(&total)
+	if err != nil {
+		return nil, 0, utils.Internal(err, "error searching data")
+	}
+
+	listQuery, listArgs := utils.AddPagination("SELECT type, id, first_name, last_name, email, class, subject, score FROM ("+union+") AS results ORDER BY score DESC, type, id", args, page, limit)
+	rows, err := s.db.QueryContext(ctx, listQuery, listArgs...)
+	if err != nil {
+		return nil, 0, utils.Internal(err, "error searching data")
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0)
+	for rows.Next() {
+		var result models.SearchResult
+		var teacher models.Teacher
+		err := rows.Scan(&result.Type, &teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject, &result.Score)
+		if err != nil {
+			return nil, 0, utils.Internal(err, "error searching data")
+		}
+
+		result.ID = teacher.ID
+		if result.Type == models.SearchTypeStudent {
+			result.Data = models.Student{ID: teacher.ID, FirstName: teacher.FirstName, LastName: teacher.LastName, Email: teacher.Email, Class: teacher.Class}
+		} else {
+			result.Data = teacher
+		}
+		results = append(results, result)
+	}
+	return results, total, rows.Err()
+}
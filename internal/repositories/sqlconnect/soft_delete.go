@@ -0,0 +1,67 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"time"
+)
+
+// notDeleted leaves the soft deleted rows out of a query ending in a WHERE clause
+func notDeleted(query string, includeDeleted bool) string {
+	if includeDeleted {
+		return query
+	}
+	return query + " AND deleted_at IS NULL"
+}
+
+// restore clears deleted_at of the row with id and bumps its version. It fails with ErrNotFound
+// when there is no such row, with ErrNotDeleted when the row is not deleted, and with a conflict
+// when a live row took its email in the meantime.
+func restore(ctx context.Context, db *sql.DB, table string, id int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.Internal(err, "error restoring data")
+	}
+
+	var deletedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT deleted_at FROM "+table+" WHERE id = ? FOR UPDATE", id).Scan(&deletedAt)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return repositories.ErrNotFound
+		}
+		return utils.Internal(err, "error restoring data")
+	}
+	if !deletedAt.Valid {
+		tx.Rollback()
+		return repositories.ErrNotDeleted
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = NULL, version = version + 1, updated_at = ? WHERE id = ?", repositories.Timestamp(), id)
+	if err != nil {
+		err = emailConflict(ctx, tx, table, "", err, "error restoring data")
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return utils.Internal(err, "error restoring data")
+	}
+	return nil
+}
+
+// purge permanently deletes the rows of table soft deleted before before
+func purge(ctx context.Context, db *sql.DB, table string, before time.Time) (int, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE deleted_at IS NOT NULL AND deleted_at < ?", before.UTC())
+	if err != nil {
+		return 0, utils.Internal(err, "error purging data")
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, utils.Internal(err, "error purging data")
+	}
+	return int(purged), nil
+}
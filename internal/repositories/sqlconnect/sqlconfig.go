@@ -1,13 +1,18 @@
 package sqlconnect
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// ConnectDb opens the connection pool shared by the whole server and makes sure the
+// database is reachable. It should be called once at startup and closed on shutdown.
 func ConnectDb() (*sql.DB, error) {
 	fmt.Println("Connecting to MariaDB...")
 	// err := godotenv.Load()
@@ -21,12 +26,43 @@ func ConnectDb() (*sql.DB, error) {
 	port := os.Getenv("DB_PORT")
 	host := os.Getenv("HOST")
 
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port, dbname)
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, password, host, port, dbname)
 	db, err := sql.Open("mysql", connectionString)
 	if err != nil {
 		// panic(err)
 		return nil, err
 	}
+
+	// pool settings, overridable from the environment
+	db.SetMaxOpenConns(envInt("DB_MAX_OPEN_CONNS", 25))
+	db.SetMaxIdleConns(envInt("DB_MAX_IDLE_CONNS", 25))
+	db.SetConnMaxLifetime(envDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	fmt.Println("Connected to MariaDB")
 	return db, nil
 }
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
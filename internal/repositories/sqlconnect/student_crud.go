@@ -0,0 +1,355 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+	"time"
+)
+
+// StudentRepo is the MySQL implementation of repositories.StudentRepository
+type StudentRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.StudentRepository = (*StudentRepo)(nil)
+
+func NewStudentRepo(db *sql.DB) *StudentRepo {
+	return &StudentRepo{db: db}
+}
+
+func scanStudent(row interface{ Scan(...interface{}) error }, student *models.Student) error {
+	return row.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class, &student.ClassID, &student.Version, &student.UpdatedAt)
+}
+
+func (s *StudentRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Student, error) {
+	columns := utils.QueryColumns(models.Student{}, opts.Fields, opts.Sort)
+	query := notDeleted("SELECT "+strings.Join(columns, ", ")+" FROM students WHERE 1=1", opts.IncludeDeleted)
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	studentList := make([]models.Student, 0)
+	for rows.Next() {
+		var student models.Student
+		err := rows.Scan(utils.ColumnPointers(&student, columns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		studentList = append(studentList, student)
+	}
+	return studentList, nil
+}
+
+func (s *StudentRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := notDeleted("SELECT COUNT(*) FROM students WHERE 1=1", opts.IncludeDeleted)
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	var total int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (s *StudentRepo) Get(ctx context.Context, id int, includeDeleted bool, fields ...string) (models.Student, error) {
+	var student models.Student
+	columns := utils.QueryColumns(models.Student{}, fields, nil)
+	query := notDeleted("SELECT "+strings.Join(columns, ", ")+" FROM students WHERE id = ?", includeDeleted)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(utils.ColumnPointers(&student, columns)...)
+	if err == sql.ErrNoRows {
+		return models.Student{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Student{}, utils.Internal(err, "error retrieving data")
+	}
+	return student, nil
+}
+
+// Create adds all students with one multi-row INSERT in a transaction. The new ids are
+// consecutive from LastInsertId, which InnoDB guarantees for a single INSERT under the default
+// innodb_autoinc_lock_mode of MariaDB.
+func (s *StudentRepo) Create(ctx context.Context, newStudents []models.Student) ([]models.Student, error) {
+	if len(newStudents) == 0 {
+		return []models.Student{}, nil
+	}
+
+	var args []interface{}
+	updatedAt := repositories.Timestamp()
+	for i := range newStudents {
+		newStudents[i].Version = 1
+		newStudents[i].UpdatedAt = updatedAt
+		newStudents[i].DeletedAt = nil
+		args = append(args, utils.GetStructValues(newStudents[i])...)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery("students", models.Student{}, len(newStudents)), args...)
+	if err != nil {
+		err = emailConflict(ctx, tx, "students", "", err, "error adding data")
+		tx.Rollback()
+		return nil, err
+	}
+
+	firstID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	addedStudents := make([]models.Student, len(newStudents))
+	for i, newStudent := range newStudents {
+		newStudent.ID = int(firstID) + i
+		addedStudents[i] = newStudent
+	}
+	return addedStudents, nil
+}
+
+// CreatePartial inserts the students one by one, each committing on its own
+func (s *StudentRepo) CreatePartial(ctx context.Context, newStudents []models.Student) []repositories.ItemResult[models.Student] {
+	results := make([]repositories.ItemResult[models.Student], len(newStudents))
+
+	stmt, err := s.db.PrepareContext(ctx, utils.GenerateInsertQuery("students", models.Student{}))
+	if err != nil {
+		err = utils.Internal(err, "error adding data")
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+	defer stmt.Close()
+
+	for i, newStudent := range newStudents {
+		newStudent.Version = 1
+		newStudent.UpdatedAt = repositories.Timestamp()
+		newStudent.DeletedAt = nil
+		res, err := stmt.ExecContext(ctx, utils.GetStructValues(newStudent)...)
+		if err != nil {
+			results[i].Err = emailConflict(ctx, s.db, "students", newStudent.Email, err, "error adding data")
+			continue
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			results[i].Err = utils.Internal(err, "error adding data")
+			continue
+		}
+		newStudent.ID = int(lastID)
+		results[i].Item = newStudent
+	}
+	return results
+}
+
+func (s *StudentRepo) Update(ctx context.Context, student models.Student) (models.Student, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Student{}, utils.Internal(err, "error updating data")
+	}
+
+	version, err := lockVersion(ctx, tx, "students", student.ID, student.Version)
+	if err != nil {
+		tx.Rollback()
+		return models.Student{}, err
+	}
+
+	var classID int
+	err = tx.QueryRowContext(ctx, "SELECT class_id FROM students WHERE id = ?", student.ID).Scan(&classID)
+	if err != nil {
+		tx.Rollback()
+		return models.Student{}, utils.Internal(err, "error updating data")
+	}
+
+	student.Version = version + 1
+	student.UpdatedAt = repositories.Timestamp()
+	student.DeletedAt = nil
+	_, err = tx.ExecContext(ctx, "UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, version = ?, updated_at = ? WHERE id = ?", student.FirstName, student.LastName, student.Email, student.Class, student.ClassID, student.Version, student.UpdatedAt, student.ID)
+	if err != nil {
+		err = emailConflict(ctx, tx, "students", student.Email, err, "error updating data")
+		tx.Rollback()
+		return models.Student{}, err
+	}
+
+	if student.ClassID != classID {
+		err = moveEnrollment(ctx, tx, student.ID, student.ClassID, models.Today())
+		if err != nil {
+			tx.Rollback()
+			return models.Student{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return models.Student{}, utils.Internal(err, "error updating data")
+	}
+	return student, nil
+}
+
+func (s *StudentRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Student, error) {
+	// start transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	updatedStudents := make([]models.Student, 0, len(patches))
+	for _, patch := range patches {
+		var studentFromDb models.Student
+		err := scanStudent(tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, class_id, version, updated_at FROM students WHERE id = ? AND deleted_at IS NULL FOR UPDATE", patch.ID), &studentFromDb)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("%w: student %d", repositories.ErrNotFound, patch.ID)
+			}
+			return nil, utils.Internal(err, "error updating data")
+		}
+
+		err = repositories.CheckVersion(patch.ID, studentFromDb.Version, patch.Version)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		classID := studentFromDb.ClassID
+		err = patch.Apply(&studentFromDb)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		studentFromDb.Version++
+		studentFromDb.UpdatedAt = repositories.Timestamp()
+		_, err = tx.ExecContext(ctx, "UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, version = ?, updated_at = ? WHERE id = ?", studentFromDb.FirstName, studentFromDb.LastName, studentFromDb.Email, studentFromDb.Class, studentFromDb.ClassID, studentFromDb.Version, studentFromDb.UpdatedAt, studentFromDb.ID)
+		if err != nil {
+			err = emailConflict(ctx, tx, "students", studentFromDb.Email, err, "error updating data")
+			tx.Rollback()
+			return nil, err
+		}
+
+		if studentFromDb.ClassID != classID {
+			err = moveEnrollment(ctx, tx, studentFromDb.ID, studentFromDb.ClassID, models.Today())
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		updatedStudents = append(updatedStudents, studentFromDb)
+	}
+
+	// commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+	return updatedStudents, nil
+}
+
+func (s *StudentRepo) Delete(ctx context.Context, id int, version int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+
+	_, err = lockVersion(ctx, tx, "students", id, version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := repositories.Timestamp()
+	_, err = tx.ExecContext(ctx, "UPDATE students SET deleted_at = ?, version = version + 1, updated_at = ? WHERE id = ?", now, now, id)
+	if err != nil {
+		tx.Rollback()
+		return utils.Internal(err, "error deleting data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	return nil
+}
+
+func (s *StudentRepo) BulkDelete(ctx context.Context, deletions []repositories.Deletion) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE students SET deleted_at = ?, version = version + 1, updated_at = ? WHERE id = ? AND deleted_at IS NULL")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	defer stmt.Close()
+
+	now := repositories.Timestamp()
+	deletedIds := []int{}
+	for _, deletion := range deletions {
+		// a repeated id is already deleted the second time and is not found
+		_, err := lockVersion(ctx, tx, "students", deletion.ID, deletion.Version)
+		if errors.Is(err, repositories.ErrNotFound) {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: ID %v does not exist", repositories.ErrNotFound, deletion.ID)
+		} else if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		_, err = stmt.ExecContext(ctx, now, now, deletion.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.Internal(err, "error deleting data")
+		}
+		deletedIds = append(deletedIds, deletion.ID)
+	}
+
+	// commit
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	return deletedIds, nil
+}
+
+func (s *StudentRepo) Restore(ctx context.Context, id int) (models.Student, error) {
+	err := restore(ctx, s.db, "students", id)
+	if err != nil {
+		return models.Student{}, err
+	}
+	return s.Get(ctx, id, false)
+}
+
+func (s *StudentRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, s.db, "students", before)
+}
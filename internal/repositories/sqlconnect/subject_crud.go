@@ -0,0 +1,260 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+	"strings"
+)
+
+// SubjectRepo is the MySQL implementation of repositories.SubjectRepository
+type SubjectRepo struct {
+	db *sql.DB
+}
+
+var _ repositories.SubjectRepository = (*SubjectRepo)(nil)
+
+func NewSubjectRepo(db *sql.DB) *SubjectRepo {
+	return &SubjectRepo{db: db}
+}
+
+func (s *SubjectRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Subject, error) {
+	columns := utils.QueryColumns(models.Subject{}, opts.Fields, opts.Sort)
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM subjects WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	subjectList := make([]models.Subject, 0)
+	for rows.Next() {
+		var subject models.Subject
+		err := rows.Scan(utils.ColumnPointers(&subject, columns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		subjectList = append(subjectList, subject)
+	}
+	return subjectList, nil
+}
+
+func (s *SubjectRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := "SELECT COUNT(*) FROM subjects WHERE 1=1"
+	var args []interface{}
+
+	query, args = utils.AddFilters(query, args, opts.Filters)
+
+	var total int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (s *SubjectRepo) Get(ctx context.Context, id int, fields ...string) (models.Subject, error) {
+	var subject models.Subject
+	columns := utils.QueryColumns(models.Subject{}, fields, nil)
+	err := s.db.QueryRowContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM subjects WHERE id = ?", id).Scan(utils.ColumnPointers(&subject, columns)...)
+	if err == sql.ErrNoRows {
+		return models.Subject{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Subject{}, utils.Internal(err, "error retrieving data")
+	}
+	return subject, nil
+}
+
+// Create adds all subjects with one multi-row INSERT, see TeacherRepo.Create for the ids
+func (s *SubjectRepo) Create(ctx context.Context, newSubjects []models.Subject) ([]models.Subject, error) {
+	if len(newSubjects) == 0 {
+		return []models.Subject{}, nil
+	}
+
+	var args []interface{}
+	for _, newSubject := range newSubjects {
+		args = append(args, utils.GetStructValues(newSubject)...)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery("subjects", models.Subject{}, len(newSubjects)), args...)
+	if err != nil {
+		err = uniqueConflict(ctx, tx, "subjects", "name", "", err, "error adding data")
+		tx.Rollback()
+		return nil, err
+	}
+
+	firstID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
+
+	addedSubjects := make([]models.Subject, len(newSubjects))
+	for i, newSubject := range newSubjects {
+		newSubject.ID = int(firstID) + i
+		addedSubjects[i] = newSubject
+	}
+	return addedSubjects, nil
+}
+
+func (s *SubjectRepo) Update(ctx context.Context, subject models.Subject) (models.Subject, error) {
+	res, err := s.db.ExecContext(ctx, "UPDATE subjects SET name = ? WHERE id = ?", subject.Name, subject.ID)
+	if err != nil {
+		return models.Subject{}, uniqueConflict(ctx, s.db, "subjects", "name", subject.Name, err, "error updating data")
+	}
+
+	// MySQL reports 0 rows for an update that changes nothing, so check that the subject exists
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return models.Subject{}, utils.Internal(err, "error updating data")
+	}
+	if rowsAffected == 0 {
+		return s.Get(ctx, subject.ID)
+	}
+	return subject, nil
+}
+
+func (s *SubjectRepo) Delete(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM subjects WHERE id = ?", id)
+	if err != nil {
+		err = foreignKeyError(err, "error deleting data")
+		if errors.Is(err, repositories.ErrInUse) {
+			return fmt.Errorf("%w: subject %d is assigned to teachers, counting deleted ones", err, id)
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	if rowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (s *SubjectRepo) ForTeacher(ctx context.Context, teacherID int) ([]models.Subject, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT subjects.id, subjects.name FROM subjects JOIN teacher_subjects ON teacher_subjects.subject_id = subjects.id WHERE teacher_subjects.teacher_id = ? ORDER BY subjects.name", teacherID)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	subjectList := make([]models.Subject, 0)
+	for rows.Next() {
+		var subject models.Subject
+		err := rows.Scan(&subject.ID, &subject.Name)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
+		}
+		subjectList = append(subjectList, subject)
+	}
+	return subjectList, nil
+}
+
+// Assign locks the teacher row while it replaces the assignments, so two assignments to the
+// same teacher do not interleave. A missing subject is caught by the foreign key.
+func (s *SubjectRepo) Assign(ctx context.Context, teacherID int, subjectIDs []int) ([]models.Subject, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	var subject string
+	err = tx.QueryRowContext(ctx, "SELECT subject FROM teachers WHERE id = ? AND deleted_at IS NULL FOR UPDATE", teacherID).Scan(&subject)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, repositories.ErrNotFound
+		}
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM teacher_subjects WHERE teacher_id = ?", teacherID)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	// INSERT IGNORE would also ignore the foreign key, so repeated ids are dropped here
+	seen := make(map[int]bool, len(subjectIDs))
+	args := make([]interface{}, 0, 2*len(subjectIDs))
+	for _, subjectID := range subjectIDs {
+		if !seen[subjectID] {
+			seen[subjectID] = true
+			args = append(args, teacherID, subjectID)
+		}
+	}
+
+	query := "INSERT INTO teacher_subjects (teacher_id, subject_id) VALUES (?, ?)" + strings.Repeat(", (?, ?)", len(seen)-1)
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, foreignKeyError(err, "error updating data")
+	}
+
+	// the subject of the teacher stays one of its subjects, the first of subjectIDs when it was left out
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET subject = (SELECT name FROM subjects WHERE id = ?), version = version + 1, updated_at = ? WHERE id = ? AND NOT EXISTS (SELECT 1 FROM teacher_subjects JOIN subjects ON subjects.id = teacher_subjects.subject_id WHERE teacher_subjects.teacher_id = ? AND subjects.name = ?)", subjectIDs[0], repositories.Timestamp(), teacherID, teacherID, subject)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+	return s.ForTeacher(ctx, teacherID)
+}
+
+// assignSubject makes the subject named subject an assignment of the teacher with teacherID in
+// place of the subject named previous, adding it to the catalogue when it is new. It keeps the
+// subject column of a teacher among its assigned subjects.
+func assignSubject(ctx context.Context, tx *sql.Tx, teacherID int, previous, subject string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO subjects (name) VALUES (?) ON DUPLICATE KEY UPDATE name = name", subject)
+	if err != nil {
+		return utils.Internal(err, "error updating data")
+	}
+
+	if previous != "" {
+		_, err = tx.ExecContext(ctx, "DELETE teacher_subjects FROM teacher_subjects JOIN subjects ON subjects.id = teacher_subjects.subject_id WHERE teacher_subjects.teacher_id = ? AND subjects.name = ?", teacherID, previous)
+		if err != nil {
+			return utils.Internal(err, "error updating data")
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO teacher_subjects (teacher_id, subject_id) SELECT ?, id FROM subjects WHERE name = ? ON DUPLICATE KEY UPDATE subject_id = subject_id", teacherID, subject)
+	if err != nil {
+		return utils.Internal(err, "error updating data")
+	}
+	return nil
+}
@@ -1,123 +1,392 @@
 package sqlconnect
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"net/http"
 	"restapi/internal/models"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
 	"strings"
+	"time"
 )
 
-func isValidSortOrder(order string) bool {
-	return order == "asc" || order == "desc"
+// TeacherRepo is the MySQL implementation of repositories.TeacherRepository
+type TeacherRepo struct {
+	db *sql.DB
 }
 
-func isValidSortField(field string) bool {
-	validFields := map[string]bool{
-		"first_name": true,
-		"last_name":  true,
-		"email":      true,
-		"class":      true,
-		"subject":    true,
-	}
-	return validFields[field]
+var _ repositories.TeacherRepository = (*TeacherRepo)(nil)
+
+func NewTeacherRepo(db *sql.DB) *TeacherRepo {
+	return &TeacherRepo{db: db}
 }
 
-func addSorting(r *http.Request, query string) string {
-	sortParams := r.URL.Query()["sortby"]
-	if len(sortParams) > 0 {
-		query += " ORDER BY"
-		for i, param := range sortParams {
-			parts := strings.Split(param, ":")
-			if len(parts) != 2 {
-				continue
-			}
-			field, order := parts[0], parts[1]
-			if !isValidSortField(field) || !isValidSortOrder(order) {
-				continue
-			}
-			if i > 0 {
-				query += ","
-			}
-			query += " " + field + " " + order
+func scanTeacher(row interface{ Scan(...interface{}) error }, teacher *models.Teacher) error {
+	return row.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.ClassID, &teacher.Subject, &teacher.Version, &teacher.UpdatedAt)
+}
+
+func (t *TeacherRepo) List(ctx context.Context, opts repositories.ListOptions) ([]models.Teacher, error) {
+	columns := utils.QueryColumns(models.Teacher{}, opts.Fields, opts.Sort)
+	query := notDeleted("SELECT "+strings.Join(columns, ", ")+" FROM teachers WHERE 1=1", opts.IncludeDeleted)
+	var args []interface{}
+
+	query, args = addTeacherFilters(query, args, opts.Filters)
+
+	query, args = utils.AddKeyset(query, args, opts.Sort, opts.After)
+
+	query = utils.AddSorting(query, opts.Sort)
+
+	page := opts.Page
+	if opts.After != nil {
+		page = 1
+	}
+	query, args = utils.AddPagination(query, args, page, opts.Limit)
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.Internal(err, "error retrieving data")
+	}
+	defer rows.Close()
+
+	teacherList := make([]models.Teacher, 0)
+	for rows.Next() {
+		var teacher models.Teacher
+		err := rows.Scan(utils.ColumnPointers(&teacher, columns)...)
+		if err != nil {
+			return nil, utils.Internal(err, "error retrieving data")
 		}
+		teacherList = append(teacherList, teacher)
 	}
-	return query
+	return teacherList, nil
 }
 
-func addFilters(r *http.Request, query string, args []interface{}) (string, []interface{}) {
-	params := map[string]string{
-		"first_name": "first_name",
-		"last_name":  "last_name",
-		"email":      "email",
-		"class":      "class",
-		"subject":    "subject",
-	}
+// addTeacherFilters is utils.AddFilters where a subject filter matches the subjects assigned to
+// the teacher, which its subject column is one of
+func addTeacherFilters(query string, args []interface{}, filters []utils.Filter) (string, []interface{}) {
+	for _, filter := range filters {
+		if filter.Field != "subject" {
+			query, args = utils.AddFilters(query, args, []utils.Filter{filter})
+			continue
+		}
 
-	for param, dbField := range params {
-		value := r.URL.Query().Get(param)
-		if value != "" {
-			query += " AND " + dbField + " = ?"
-			args = append(args, value)
+		// a teacher with Math and Art is not subject[ne]=Math, so a negative filter is the
+		// teacher not matching its positive one on any of its subjects
+		filter, negated := utils.Positive(filter)
+		filter.Field = "subjects.name"
+		assigned, assignedArgs := utils.AddFilters("", nil, []utils.Filter{filter})
+		condition := "id IN (SELECT teacher_subjects.teacher_id FROM teacher_subjects JOIN subjects ON subjects.id = teacher_subjects.subject_id WHERE " +
+			strings.TrimPrefix(assigned, " AND ") + ")"
+		if negated {
+			condition = "NOT " + condition
 		}
+		query += " AND " + condition
+		args = append(args, assignedArgs...)
 	}
 	return query, args
 }
 
-func GetTeachersDbHandler(r *http.Request) ([]models.Teacher, bool) {
-	db, err := ConnectDb()
+func (t *TeacherRepo) Count(ctx context.Context, opts repositories.ListOptions) (int, error) {
+	query := notDeleted("SELECT COUNT(*) FROM teachers WHERE 1=1", opts.IncludeDeleted)
+	var args []interface{}
+
+	query, args = addTeacherFilters(query, args, opts.Filters)
+
+	var total int
+	err := t.db.QueryRowContext(ctx, query, args...).Scan(&total)
 	if err != nil {
-		// http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		return nil, true
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return total, nil
+}
+
+func (t *TeacherRepo) Get(ctx context.Context, id int, includeDeleted bool, fields ...string) (models.Teacher, error) {
+	var teacher models.Teacher
+	columns := utils.QueryColumns(models.Teacher{}, fields, nil)
+	query := notDeleted("SELECT "+strings.Join(columns, ", ")+" FROM teachers WHERE id = ?", includeDeleted)
+	err := t.db.QueryRowContext(ctx, query, id).Scan(utils.ColumnPointers(&teacher, columns)...)
+	if err == sql.ErrNoRows {
+		return models.Teacher{}, repositories.ErrNotFound
+	} else if err != nil {
+		return models.Teacher{}, utils.Internal(err, "error retrieving data")
+	}
+	return teacher, nil
+}
+
+// Create adds all teachers with one multi-row INSERT in a transaction, then assigns each its
+// subject. The new ids are consecutive from LastInsertId, which InnoDB guarantees for a single
+// INSERT under the default innodb_autoinc_lock_mode of MariaDB.
+func (t *TeacherRepo) Create(ctx context.Context, newTeachers []models.Teacher) ([]models.Teacher, error) {
+	if len(newTeachers) == 0 {
+		return []models.Teacher{}, nil
 	}
-	defer db.Close()
 
-	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
 	var args []interface{}
+	updatedAt := repositories.Timestamp()
+	for i := range newTeachers {
+		newTeachers[i].Version = 1
+		newTeachers[i].UpdatedAt = updatedAt
+		newTeachers[i].DeletedAt = nil
+		args = append(args, utils.GetStructValues(newTeachers[i])...)
+	}
 
-	query, args = addFilters(r, query, args)
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
+	}
 
-	query = addSorting(r, query)
+	res, err := tx.ExecContext(ctx, utils.GenerateBulkInsertQuery("teachers", models.Teacher{}, len(newTeachers)), args...)
+	if err != nil {
+		err = emailConflict(ctx, tx, "teachers", "", err, "error adding data")
+		tx.Rollback()
+		return nil, err
+	}
 
-	rows, err := db.Query(query, args...)
+	firstID, err := res.LastInsertId()
 	if err != nil {
-		fmt.Println(err)
-		// http.Error(w, "Database query error", http.StatusInternalServerError)
-		return nil, true
+		tx.Rollback()
+		return nil, utils.Internal(err, "error adding data")
 	}
-	defer rows.Close()
 
-	teacherList := make([]models.Teacher, 0)
-	for rows.Next() {
-		var teacher models.Teacher
-		err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
+	addedTeachers := make([]models.Teacher, len(newTeachers))
+	for i, newTeacher := range newTeachers {
+		newTeacher.ID = int(firstID) + i
+		err = assignSubject(ctx, tx, newTeacher.ID, "", newTeacher.Subject)
 		if err != nil {
-			// http.Error(w, "Error scanning database results", http.StatusInternalServerError)
-			return nil, true
+			tx.Rollback()
+			return nil, err
 		}
-		teacherList = append(teacherList, teacher)
+		addedTeachers[i] = newTeacher
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error adding data")
 	}
-	return teacherList, false
+	return addedTeachers, nil
 }
 
-func GetOneTeacherDbHandler(w http.ResponseWriter, id int) (models.Teacher, bool) {
-	db, err := ConnectDb()
+// CreatePartial inserts the teachers one by one, each with its subject in a transaction of its own
+func (t *TeacherRepo) CreatePartial(ctx context.Context, newTeachers []models.Teacher) []repositories.ItemResult[models.Teacher] {
+	results := make([]repositories.ItemResult[models.Teacher], len(newTeachers))
+	for i, newTeacher := range newTeachers {
+		results[i].Item, results[i].Err = t.createOne(ctx, newTeacher)
+	}
+	return results
+}
+
+func (t *TeacherRepo) createOne(ctx context.Context, newTeacher models.Teacher) (models.Teacher, error) {
+	newTeacher.Version = 1
+	newTeacher.UpdatedAt = repositories.Timestamp()
+	newTeacher.DeletedAt = nil
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
-		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
-		return models.Teacher{}, true
+		return models.Teacher{}, utils.Internal(err, "error adding data")
 	}
-	defer db.Close()
 
-	var teacher models.Teacher
+	res, err := tx.ExecContext(ctx, utils.GenerateInsertQuery("teachers", models.Teacher{}), utils.GetStructValues(newTeacher)...)
+	if err != nil {
+		err = emailConflict(ctx, tx, "teachers", newTeacher.Email, err, "error adding data")
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.Teacher{}, utils.Internal(err, "error adding data")
+	}
+	newTeacher.ID = int(lastID)
 
-	err = db.QueryRow("SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?", id).Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.Class, &teacher.Subject)
+	err = assignSubject(ctx, tx, newTeacher.ID, "", newTeacher.Subject)
+	if err != nil {
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
 
-	if err == sql.ErrNoRows {
-		http.Error(w, "Teacher not found", http.StatusNotFound)
-		return models.Teacher{}, true
-	} else if err != nil {
-		http.Error(w, "Database query error", http.StatusInternalServerError)
-		return models.Teacher{}, true
+	err = tx.Commit()
+	if err != nil {
+		return models.Teacher{}, utils.Internal(err, "error adding data")
+	}
+	return newTeacher, nil
+}
+
+func (t *TeacherRepo) Update(ctx context.Context, teacher models.Teacher) (models.Teacher, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Teacher{}, utils.Internal(err, "error updating data")
+	}
+
+	version, err := lockVersion(ctx, tx, "teachers", teacher.ID, teacher.Version)
+	if err != nil {
+		tx.Rollback()
+		return models.Teacher{}, err
 	}
-	return teacher, false
+
+	var previous string
+	err = tx.QueryRowContext(ctx, "SELECT subject FROM teachers WHERE id = ?", teacher.ID).Scan(&previous)
+	if err != nil {
+		tx.Rollback()
+		return models.Teacher{}, utils.Internal(err, "error updating data")
+	}
+
+	teacher.Version = version + 1
+	teacher.UpdatedAt = repositories.Timestamp()
+	teacher.DeletedAt = nil
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, subject = ?, version = ?, updated_at = ? WHERE id = ?", teacher.FirstName, teacher.LastName, teacher.Email, teacher.Class, teacher.ClassID, teacher.Subject, teacher.Version, teacher.UpdatedAt, teacher.ID)
+	if err != nil {
+		err = emailConflict(ctx, tx, "teachers", teacher.Email, err, "error updating data")
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
+
+	err = assignSubject(ctx, tx, teacher.ID, previous, teacher.Subject)
+	if err != nil {
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return models.Teacher{}, utils.Internal(err, "error updating data")
+	}
+	return teacher, nil
+}
+
+func (t *TeacherRepo) Patch(ctx context.Context, patches []repositories.Patch) ([]models.Teacher, error) {
+	// start transaction
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+
+	updatedTeachers := make([]models.Teacher, 0, len(patches))
+	for _, patch := range patches {
+		var teacherFromDb models.Teacher
+		err := scanTeacher(tx.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, class, class_id, subject, version, updated_at FROM teachers WHERE id = ? AND deleted_at IS NULL FOR UPDATE", patch.ID), &teacherFromDb)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("%w: teacher %d", repositories.ErrNotFound, patch.ID)
+			}
+			return nil, utils.Internal(err, "error updating data")
+		}
+
+		err = repositories.CheckVersion(patch.ID, teacherFromDb.Version, patch.Version)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		previous := teacherFromDb.Subject
+		err = patch.Apply(&teacherFromDb)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		teacherFromDb.Version++
+		teacherFromDb.UpdatedAt = repositories.Timestamp()
+		_, err = tx.ExecContext(ctx, "UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, class_id = ?, subject = ?, version = ?, updated_at = ? WHERE id = ?", teacherFromDb.FirstName, teacherFromDb.LastName, teacherFromDb.Email, teacherFromDb.Class, teacherFromDb.ClassID, teacherFromDb.Subject, teacherFromDb.Version, teacherFromDb.UpdatedAt, teacherFromDb.ID)
+		if err != nil {
+			err = emailConflict(ctx, tx, "teachers", teacherFromDb.Email, err, "error updating data")
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = assignSubject(ctx, tx, teacherFromDb.ID, previous, teacherFromDb.Subject)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		updatedTeachers = append(updatedTeachers, teacherFromDb)
+	}
+
+	// commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error updating data")
+	}
+	return updatedTeachers, nil
+}
+
+func (t *TeacherRepo) Delete(ctx context.Context, id int, version int) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+
+	_, err = lockVersion(ctx, tx, "teachers", id, version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := repositories.Timestamp()
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET deleted_at = ?, version = version + 1, updated_at = ? WHERE id = ?", now, now, id)
+	if err != nil {
+		tx.Rollback()
+		return utils.Internal(err, "error deleting data")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return utils.Internal(err, "error deleting data")
+	}
+	return nil
+}
+
+func (t *TeacherRepo) BulkDelete(ctx context.Context, deletions []repositories.Deletion) ([]int, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE teachers SET deleted_at = ?, version = version + 1, updated_at = ? WHERE id = ? AND deleted_at IS NULL")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	defer stmt.Close()
+
+	now := repositories.Timestamp()
+	deletedIds := []int{}
+	for _, deletion := range deletions {
+		// a repeated id is already deleted the second time and is not found
+		_, err := lockVersion(ctx, tx, "teachers", deletion.ID, deletion.Version)
+		if errors.Is(err, repositories.ErrNotFound) {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: ID %v does not exist", repositories.ErrNotFound, deletion.ID)
+		} else if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		_, err = stmt.ExecContext(ctx, now, now, deletion.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.Internal(err, "error deleting data")
+		}
+		deletedIds = append(deletedIds, deletion.ID)
+	}
+
+	// commit
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.Internal(err, "error deleting data")
+	}
+	return deletedIds, nil
+}
+
+func (t *TeacherRepo) Restore(ctx context.Context, id int) (models.Teacher, error) {
+	err := restore(ctx, t.db, "teachers", id)
+	if err != nil {
+		return models.Teacher{}, err
+	}
+	return t.Get(ctx, id, false)
+}
+
+func (t *TeacherRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, t.db, "teachers", before)
 }
@@ -0,0 +1,22 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"restapi/internal/repositories"
+	"restapi/pkg/utils"
+)
+
+// lockVersion locks the row with id until tx ends and returns its version. It fails with
+// ErrNotFound when there is no such row or it is soft deleted, and with ErrVersionMismatch when expected is not 0
+// and the row is at another version.
+func lockVersion(ctx context.Context, tx *sql.Tx, table string, id, expected int) (int, error) {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM "+table+" WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, repositories.ErrNotFound
+	} else if err != nil {
+		return 0, utils.Internal(err, "error retrieving data")
+	}
+	return version, repositories.CheckVersion(id, version, expected)
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Cursor marks the last row of a keyset page: its values for the sort columns and its id.
+// Sort records the sortby spec the cursor was made for so it can't be replayed with another order.
+// Nulls marks the values that are NULL, which Values holds as "".
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	Nulls  []bool   `json:"n"`
+	ID     int      `json:"id"`
+}
+
+var ErrInvalidCursor = Validation("invalid cursor")
+
+// errNoCursorSecret is returned instead of signing with an empty key, which anyone could forge
+var errNoCursorSecret = errors.New("CURSOR_SECRET and JWT_SECRET are both unset")
+
+func cursorSecret() []byte {
+	secret := os.Getenv("CURSOR_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	return []byte(secret)
+}
+
+// CheckCursorSecret fails when there is no key to sign cursors with, so the server can refuse to start
+func CheckCursorSecret() error {
+	if len(cursorSecret()) == 0 {
+		return errNoCursorSecret
+	}
+	return nil
+}
+
+func signCursor(payload string) (string, error) {
+	secret := cursorSecret()
+	if len(secret) == 0 {
+		return "", Internal(errNoCursorSecret, "cursors cannot be signed")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SortKey is the canonical form of a sortby spec, e.g. "first_name:asc,id:asc"
+func SortKey(sorts []SortField) string {
+	parts := make([]string, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		parts = append(parts, sort.Field+":"+sort.Order)
+	}
+	parts = append(parts, "id:asc")
+	return strings.Join(parts, ",")
+}
+
+// NewCursor builds the cursor pointing after row, which must have db tags for the sort columns
+func NewCursor(sorts []SortField, row interface{}) Cursor {
+	cursor := Cursor{Sort: SortKey(sorts)}
+	for _, sort := range sorts {
+		cursor.Values = append(cursor.Values, GetColumnValue(row, sort.Field))
+		cursor.Nulls = append(cursor.Nulls, IsNullColumn(row, sort.Field))
+	}
+	cursor.ID = GetColumnInt(row, "id")
+	return cursor
+}
+
+// EncodeCursor turns a cursor into the opaque, signed string sent to the client
+func EncodeCursor(cursor Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", Internal(err, "error encoding cursor")
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	signature, err := signCursor(payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + signature, nil
+}
+
+// DecodeCursor verifies the signature of an encoded cursor and that it was made for sorts
+func DecodeCursor(encoded string, sorts []SortField) (*Cursor, error) {
+	payload, signature, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	expected, err := signCursor(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Sort != SortKey(sorts) || len(cursor.Values) != len(sorts) || len(cursor.Nulls) != len(sorts) {
+		return nil, fmt.Errorf("%w: it does not match the sortby parameters", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// AddKeyset restricts the query to the rows after cursor in the order given by sorts, then id.
+// For sorts a asc, b desc it adds (a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?).
+// A NULL value of the cursor is matched with IS NULL, and sorts before every other value like
+// it does in MySQL.
+func AddKeyset(query string, args []interface{}, sorts []SortField, cursor *Cursor) (string, []interface{}) {
+	if cursor == nil {
+		return query, args
+	}
+
+	var clauses []string
+	for i := 0; i <= len(sorts); i++ {
+		var parts []string
+		var clauseArgs []interface{}
+		for j := 0; j < i; j++ {
+			if cursor.isNull(j) {
+				parts = append(parts, sorts[j].Field+" IS NULL")
+				continue
+			}
+			parts = append(parts, sorts[j].Field+" = ?")
+			clauseArgs = append(clauseArgs, cursor.Values[j])
+		}
+		if i < len(sorts) {
+			after, afterArgs, ok := cursor.after(sorts[i], i)
+			if !ok {
+				continue
+			}
+			parts = append(parts, after)
+			clauseArgs = append(clauseArgs, afterArgs...)
+		} else {
+			parts = append(parts, "id > ?")
+			clauseArgs = append(clauseArgs, cursor.ID)
+		}
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		args = append(args, clauseArgs...)
+	}
+
+	query += " AND (" + strings.Join(clauses, " OR ") + ")"
+	return query, args
+}
+
+func (c Cursor) isNull(i int) bool {
+	return i < len(c.Nulls) && c.Nulls[i]
+}
+
+// after returns the condition for a value of the sort column coming after the cursor's value at
+// i. Nothing comes after a NULL in descending order, which is reported with ok false.
+func (c Cursor) after(sort SortField, i int) (condition string, args []interface{}, ok bool) {
+	switch {
+	case c.isNull(i) && sort.Order == "desc":
+		return "", nil, false
+	case c.isNull(i):
+		return sort.Field + " IS NOT NULL", nil, true
+	case sort.Order == "desc":
+		return "(" + sort.Field + " < ? OR " + sort.Field + " IS NULL)", []interface{}{c.Values[i]}, true
+	default:
+		return sort.Field + " > ?", []interface{}{c.Values[i]}, true
+	}
+}
+
+// CursorLinkHeader builds an RFC 8288 Link header pointing at the next keyset page
+func CursorLinkHeader(r *http.Request, nextCursor string) string {
+	if nextCursor == "" {
+		return ""
+	}
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="next"`
+}
@@ -0,0 +1,110 @@
+package utils_test
+
+import (
+	"errors"
+	"reflect"
+	"restapi/pkg/utils"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID        int        `db:"id"`
+	LastName  string     `db:"last_name"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func TestCursorSigning(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "test secret")
+	sorts := []utils.SortField{{Field: "last_name", Order: "asc"}}
+
+	encoded, err := utils.EncodeCursor(utils.NewCursor(sorts, row{ID: 7, LastName: "Smith"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := utils.DecodeCursor(encoded, sorts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ID != 7 || !reflect.DeepEqual(cursor.Values, []string{"Smith"}) || !reflect.DeepEqual(cursor.Nulls, []bool{false}) {
+		t.Errorf("got %+v", cursor)
+	}
+
+	// a cursor is only good for the order it was made for, with the key it was signed with
+	_, err = utils.DecodeCursor(encoded, []utils.SortField{{Field: "last_name", Order: "desc"}})
+	if !errors.Is(err, utils.ErrInvalidCursor) {
+		t.Errorf("decoded a cursor for another order: %v", err)
+	}
+	_, err = utils.DecodeCursor(encoded+"x", sorts)
+	if !errors.Is(err, utils.ErrInvalidCursor) {
+		t.Errorf("decoded a tampered cursor: %v", err)
+	}
+	t.Setenv("CURSOR_SECRET", "other secret")
+	_, err = utils.DecodeCursor(encoded, sorts)
+	if !errors.Is(err, utils.ErrInvalidCursor) {
+		t.Errorf("decoded a cursor signed with another key: %v", err)
+	}
+}
+
+func TestCursorSecret(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	if utils.CheckCursorSecret() == nil {
+		t.Error("no secret passed the check")
+	}
+	_, err := utils.EncodeCursor(utils.Cursor{})
+	if err == nil {
+		t.Error("signed a cursor with an empty key")
+	}
+
+	t.Setenv("JWT_SECRET", "jwt secret")
+	if err := utils.CheckCursorSecret(); err != nil {
+		t.Errorf("JWT_SECRET is not used as the fallback: %v", err)
+	}
+}
+
+func TestAddKeyset(t *testing.T) {
+	at := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name  string
+		sorts []utils.SortField
+		row   row
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "asc",
+			sorts: []utils.SortField{{Field: "last_name", Order: "asc"}},
+			row:   row{ID: 3, LastName: "Smith"},
+			query: " AND ((last_name > ?) OR (last_name = ? AND id > ?))",
+			args:  []interface{}{"Smith", "Smith", 3},
+		},
+		{
+			name:  "desc reaches the NULLs sorted last",
+			sorts: []utils.SortField{{Field: "deleted_at", Order: "desc"}},
+			row:   row{ID: 3, DeletedAt: &at},
+			query: " AND (((deleted_at < ? OR deleted_at IS NULL)) OR (deleted_at = ? AND id > ?))",
+			args:  []interface{}{"2026-10-01 08:00:00", "2026-10-01 08:00:00", 3},
+		},
+		{
+			name:  "asc after a NULL",
+			sorts: []utils.SortField{{Field: "deleted_at", Order: "asc"}},
+			row:   row{ID: 3},
+			query: " AND ((deleted_at IS NOT NULL) OR (deleted_at IS NULL AND id > ?))",
+			args:  []interface{}{3},
+		},
+		{
+			name:  "desc after a NULL",
+			sorts: []utils.SortField{{Field: "deleted_at", Order: "desc"}, {Field: "last_name", Order: "asc"}},
+			row:   row{ID: 3, LastName: "Smith"},
+			query: " AND ((deleted_at IS NULL AND last_name > ?) OR (deleted_at IS NULL AND last_name = ? AND id > ?))",
+			args:  []interface{}{"Smith", "Smith", 3},
+		},
+	} {
+		cursor := utils.NewCursor(test.sorts, test.row)
+		query, args := utils.AddKeyset("", nil, test.sorts, &cursor)
+		if query != test.query || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%s: got %q %v, want %q %v", test.name, query, args, test.query, test.args)
+		}
+	}
+}
@@ -2,66 +2,142 @@ package utils
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// SortField is one "field:order" entry of the sortby query param
+type SortField struct {
+	Field string
+	Order string
+}
+
 func isValidSortOrder(order string) bool {
 	return order == "asc" || order == "desc"
 }
 
-func isValidSortField(field string) bool {
-	validFields := map[string]bool{
-		"first_name": true,
-		"last_name":  true,
-		"email":      true,
-		"class":      true,
-		"subject":    true,
+func isAllowedField(field string, allowedFields []string) bool {
+	return ContainsString(allowedFields, field)
+}
+
+func ContainsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
-	return validFields[field]
+	return false
 }
 
-func AddSorting(r *http.Request, query string) string {
-	sortParams := r.URL.Query()["sortby"]
-	if len(sortParams) > 0 {
-		query += " ORDER BY"
-		for i, param := range sortParams {
-			parts := strings.Split(param, ":")
-			if len(parts) != 2 {
-				continue
-			}
-			field, order := parts[0], parts[1]
-			if !isValidSortField(field) || !isValidSortOrder(order) {
-				continue
+// ParseSorting reads the sortby params, skipping any field not in allowedFields or with an invalid order
+func ParseSorting(r *http.Request, allowedFields []string) []SortField {
+	var sorts []SortField
+	for _, param := range r.URL.Query()["sortby"] {
+		parts := strings.Split(param, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		field, order := parts[0], parts[1]
+		if !isAllowedField(field, allowedFields) || !isValidSortOrder(order) {
+			continue
+		}
+		sorts = append(sorts, SortField{Field: field, Order: order})
+	}
+	return sorts
+}
+
+// AddSorting always ends the ORDER BY with id so pages come back in a stable order
+func AddSorting(query string, sorts []SortField) string {
+	query += " ORDER BY"
+	for _, sort := range sorts {
+		query += " " + sort.Field + " " + sort.Order + ","
+	}
+	query += " id asc"
+	return query
+}
+
+// GetColumnValue returns the value of the struct field tagged with the db column, as a string.
+// Times are formatted like a DATETIME literal so they can be compared and sent back to MySQL,
+// and NULL is the empty string.
+func GetColumnValue(model interface{}, column string) string {
+	modelValue := reflect.ValueOf(model)
+	modelType := modelValue.Type()
+	for i := 0; i < modelType.NumField(); i++ {
+		if strings.TrimSuffix(modelType.Field(i).Tag.Get("db"), ",omitempty") == column {
+			field := modelValue.Field(i)
+			if field.Kind() == reflect.Ptr {
+				// a nil pointer is a NULL column
+				if field.IsNil() {
+					return ""
+				}
+				field = field.Elem()
 			}
-			if i > 0 {
-				query += ","
+			if value, ok := field.Interface().(time.Time); ok {
+				return value.Format(time.DateTime)
 			}
-			query += " " + field + " " + order
+			return fmt.Sprint(field.Interface())
 		}
 	}
-	return query
+	return ""
 }
 
-func AddFilters(r *http.Request, query string, args []interface{}) (string, []interface{}) {
-	params := map[string]string{
-		"first_name": "first_name",
-		"last_name":  "last_name",
-		"email":      "email",
-		"class":      "class",
-		"subject":    "subject",
+// QueryColumns lists the db columns of model to SELECT, in struct order. With no fields that is
+// every column, otherwise id, version, updated_at, the requested fields and the sort columns the
+// cursor needs.
+func QueryColumns(model interface{}, fields []string, sorts []SortField) []string {
+	wanted := map[string]bool{"id": true, "version": true, "updated_at": true}
+	for _, field := range fields {
+		wanted[field] = true
+	}
+	for _, sort := range sorts {
+		wanted[sort.Field] = true
 	}
 
-	for param, dbField := range params {
-		value := r.URL.Query().Get(param)
-		if value != "" {
-			query += " AND " + dbField + " = ?"
-			args = append(args, value)
+	modelType := reflect.TypeOf(model)
+	columns := []string{}
+	for i := 0; i < modelType.NumField(); i++ {
+		column := strings.TrimSuffix(modelType.Field(i).Tag.Get("db"), ",omitempty")
+		if column != "" && (len(fields) == 0 || wanted[column]) {
+			columns = append(columns, column)
 		}
 	}
-	return query, args
+	return columns
+}
+
+// ColumnPointers returns pointers to the fields of the struct model points to, in the order of columns, for Scan
+func ColumnPointers(model interface{}, columns []string) []interface{} {
+	modelValue := reflect.ValueOf(model).Elem()
+	modelType := modelValue.Type()
+	pointers := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		for i := 0; i < modelType.NumField(); i++ {
+			if strings.TrimSuffix(modelType.Field(i).Tag.Get("db"), ",omitempty") == column {
+				pointers = append(pointers, modelValue.Field(i).Addr().Interface())
+				break
+			}
+		}
+	}
+	return pointers
+}
+
+// IsNullColumn reports whether column of model is NULL, which only a nil pointer field is
+func IsNullColumn(model interface{}, column string) bool {
+	modelValue := reflect.ValueOf(model)
+	for i := 0; i < modelValue.NumField(); i++ {
+		if strings.TrimSuffix(modelValue.Type().Field(i).Tag.Get("db"), ",omitempty") == column {
+			field := modelValue.Field(i)
+			return field.Kind() == reflect.Ptr && field.IsNil()
+		}
+	}
+	return false
+}
+
+func GetColumnInt(model interface{}, column string) int {
+	value, _ := strconv.Atoi(GetColumnValue(model, column))
+	return value
 }
 
 func GenerateInsertQuery(tableName, model interface{}) string {
@@ -69,7 +145,6 @@ func GenerateInsertQuery(tableName, model interface{}) string {
 	var columns, placeholders string
 	for i := 0; i < modelType.NumField(); i++ {
 		dbTag := modelType.Field(i).Tag.Get("db")
-		fmt.Println("DbTag: ", dbTag)
 		dbTag = strings.TrimSuffix(dbTag, ",omitempty")
 
 		if dbTag != "" && dbTag != "id" { // skip the id field if it's auto increment
@@ -84,6 +159,27 @@ func GenerateInsertQuery(tableName, model interface{}) string {
 	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, columns, placeholders)
 }
 
+// GenerateBulkInsertQuery is GenerateInsertQuery with a group of placeholders for each of rows
+func GenerateBulkInsertQuery(tableName string, model interface{}, rows int) string {
+	query := GenerateInsertQuery(tableName, model)
+	_, group, _ := strings.Cut(query, " VALUES ")
+	return query + strings.Repeat(", "+group, rows-1)
+}
+
+// GenerateUpdateQuery writes every column of model but id to the row with id, with the
+// values of GetStructValues followed by the id
+func GenerateUpdateQuery(tableName string, model interface{}) string {
+	modelType := reflect.TypeOf(model)
+	var assignments []string
+	for i := 0; i < modelType.NumField(); i++ {
+		dbTag := strings.TrimSuffix(modelType.Field(i).Tag.Get("db"), ",omitempty")
+		if dbTag != "" && dbTag != "id" {
+			assignments = append(assignments, dbTag+" = ?")
+		}
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", tableName, strings.Join(assignments, ", "))
+}
+
 func GetStructValues(model interface{}) []interface{} {
 	modelValue := reflect.ValueOf(model)
 	modelType := modelValue.Type()
@@ -94,6 +190,5 @@ func GetStructValues(model interface{}) []interface{} {
 			values = append(values, modelValue.Field(i).Interface())
 		}
 	}
-	log.Println("Values:", values)
 	return values
 }
@@ -1,13 +0,0 @@
-package utils
-
-import (
-	"fmt"
-	"log"
-	"os"
-)
-
-func ErrorHandler(err error, message string) error {
-	errorLogger := log.New(os.Stderr, "Error: ", log.Ldate|log.Ltime|log.Lshortfile)
-	errorLogger.Println(message, err)
-	return fmt.Errorf("%s", message)
-}
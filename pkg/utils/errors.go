@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+)
+
+// ProblemTypeBase prefixes the type of every problem+json response sent for an *Error
+const ProblemTypeBase = "urn:restapi:problem:"
+
+var errorLogger = log.New(os.Stderr, "Error: ", log.Ldate|log.Ltime|log.Lshortfile)
+
+// Error is an error with a known meaning for the client. WriteError sends it as an
+// RFC 7807 problem. Err is the underlying cause, which is logged but never sent.
+type Error struct {
+	Status     int
+	Type       string
+	Detail     string
+	Err        error
+	Extensions map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// With adds an extension member, like the invalid fields of a validation error, to the problem
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func NotFound(detail string) *Error {
+	return &Error{Status: http.StatusNotFound, Type: "not-found", Detail: detail}
+}
+
+func Validation(detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: "validation", Detail: detail}
+}
+
+func Conflict(detail string) *Error {
+	return &Error{Status: http.StatusConflict, Type: "conflict", Detail: detail}
+}
+
+func Unauthorized(detail string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Type: "unauthorized", Detail: detail}
+}
+
+func Forbidden(detail string) *Error {
+	return &Error{Status: http.StatusForbidden, Type: "forbidden", Detail: detail}
+}
+
+// MethodNotAllowed is for a path that has routes, none of them for the request method
+func MethodNotAllowed(detail string) *Error {
+	return &Error{Status: http.StatusMethodNotAllowed, Type: "method-not-allowed", Detail: detail}
+}
+
+// PreconditionFailed is for a conditional request, like an If-Match, that does not hold
+func PreconditionFailed(detail string) *Error {
+	return &Error{Status: http.StatusPreconditionFailed, Type: "precondition-failed", Detail: detail}
+}
+
+// PreconditionRequired is for a write that must be conditional but was sent without the header
+func PreconditionRequired(detail string) *Error {
+	return &Error{Status: http.StatusPreconditionRequired, Type: "precondition-required", Detail: detail}
+}
+
+// Internal logs err and hides it behind detail, so database and library errors never reach the client.
+// An err that already is an *Error was logged where it was made and is not logged again.
+func Internal(err error, detail string) *Error {
+	var logged *Error
+	if !errors.As(err, &logged) {
+		errorLogger.Output(2, detail+" "+errString(err))
+	}
+	return &Error{Status: http.StatusInternalServerError, Type: "internal", Detail: detail, Err: err}
+}
+
+// NewError is for the remaining statuses, like 429, which need no problem type of their own
+func NewError(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Problem builds the RFC 7807 body for err and returns it with its status. An *Error anywhere
+// in the chain picks the status. Client errors send the whole message, including context added
+// by wrapping, while anything else is logged and sent as a generic internal error.
+func Problem(r *http.Request, err error) (int, map[string]interface{}) {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		appErr = Internal(err, "internal server error")
+	}
+
+	detail := err.Error()
+	if appErr.Status >= http.StatusInternalServerError {
+		detail = appErr.Detail
+	}
+
+	problemType := "about:blank"
+	if appErr.Type != "" {
+		problemType = ProblemTypeBase + appErr.Type
+	}
+
+	problem := make(map[string]interface{}, len(appErr.Extensions)+6)
+	for key, value := range appErr.Extensions {
+		problem[key] = value
+	}
+	problem["type"] = problemType
+	problem["title"] = http.StatusText(appErr.Status)
+	problem["status"] = appErr.Status
+	problem["detail"] = detail
+	problem["instance"] = r.URL.Path
+	if requestID, ok := r.Context().Value(ContextKey("requestId")).(string); ok {
+		problem["request_id"] = requestID
+	}
+	return appErr.Status, problem
+}
+
+// WriteError is the single place errors turn into responses
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, problem := Problem(r, err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
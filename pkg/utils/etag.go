@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidEntityTag = errors.New("invalid entity tag")
+
+// ETag is the strong entity tag of a record at version
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch returns the record versions an If-Match header value allows, or nil for "*",
+// which matches any version. The value is "*" or a comma separated list of entity tags, RFC 9110
+// section 13.1.1. A weak tag never matches under the strong comparison If-Match uses, so it is
+// skipped like any tag that is not one of ours. The precondition fails when no tag is left.
+func ParseIfMatch(header string) ([]int, error) {
+	failed := PreconditionFailed("If-Match " + header + " does not match the current ETag")
+	if strings.TrimSpace(header) == "*" {
+		return nil, nil
+	}
+
+	tags, err := parseEntityTags(header)
+	if err != nil {
+		return nil, failed
+	}
+	var versions []int
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, failed
+	}
+	return versions, nil
+}
+
+// parseEntityTags splits a list of entity tags like `"1", W/"2"` into its tags, with their
+// quotes and weak prefix. A tag is quoted and may itself hold commas, so the list is scanned
+// rather than split on them.
+func parseEntityTags(header string) ([]string, error) {
+	var tags []string
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+		start := 0
+		if strings.HasPrefix(rest, "W/") {
+			start = 2
+		}
+		if !strings.HasPrefix(rest[start:], `"`) {
+			return nil, errInvalidEntityTag
+		}
+		end := strings.IndexByte(rest[start+1:], '"')
+		if end < 0 {
+			return nil, errInvalidEntityTag
+		}
+		end += start + 2
+		tags = append(tags, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, errInvalidEntityTag
+		}
+	}
+	return tags, nil
+}
+
+// ContentETag is the strong entity tag of a response body that is not a single record, like a
+// page of a list. It changes whenever any byte of body does.
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified reports whether a GET or HEAD can be answered with 304 because the copy the client
+// has is still current. If-None-Match takes precedence over If-Modified-Since, which is only
+// checked when lastModified is not zero.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if strings.TrimSpace(header) == "*" {
+			return true
+		}
+		// If-None-Match uses the weak comparison
+		tags, _ := parseEntityTags(header)
+		for _, tag := range tags {
+			if strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
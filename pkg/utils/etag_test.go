@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"restapi/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	for header, want := range map[string][]int{
+		`*`:               nil,
+		`"3"`:             {3},
+		` "3" , "7"`:      {3, 7},
+		`W/"3", "7"`:      {7},
+		`"a,b", "4"`:      {4},
+		`"3",,"5"`:        {3, 5},
+		`"3", W/"7", "x"`: {3},
+		`"0", "1"`:        {1},
+		"\t\"2\",\t\"6\"": {2, 6},
+	} {
+		versions, err := utils.ParseIfMatch(header)
+		if err != nil || !reflect.DeepEqual(versions, want) {
+			t.Errorf("%s: got %v, %v, want %v", header, versions, err, want)
+		}
+	}
+
+	// none of these can match a version of ours
+	for _, header := range []string{`W/"3"`, `"x"`, `3`, `"3`, `"3" "4"`, `"3", 4`, `*, "3"`, ``} {
+		_, err := utils.ParseIfMatch(header)
+		if err == nil {
+			t.Errorf("%s: got no error", header)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		header, value string
+		want          bool
+	}{
+		{"If-None-Match", `"1"`, true},
+		{"If-None-Match", `"2", W/"1"`, true},
+		{"If-None-Match", `*`, true},
+		{"If-None-Match", `"2"`, false},
+		{"If-Modified-Since", modified.Format(http.TimeFormat), true},
+		{"If-Modified-Since", modified.Add(-time.Second).Format(http.TimeFormat), false},
+	} {
+		r := httptest.NewRequest("GET", "/teachers/1", nil)
+		r.Header.Set(test.header, test.value)
+		if got := utils.NotModified(r, utils.ETag(1), modified); got != test.want {
+			t.Errorf("%s: %s: got %v", test.header, test.value, got)
+		}
+	}
+
+	r := httptest.NewRequest("PATCH", "/teachers/1", nil)
+	r.Header.Set("If-None-Match", `"1"`)
+	if utils.NotModified(r, utils.ETag(1), modified) {
+		t.Error("a PATCH was not modified")
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Filter operators, written as field[op]=value in the query string. A bare field=value is eq.
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpLike = "like"
+	OpIn   = "in"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpNull = "null"
+)
+
+var (
+	// TextOperators suit string columns
+	TextOperators = []string{OpEq, OpNe, OpLike, OpIn, OpNull}
+	// NumberOperators suit numeric columns such as id
+	NumberOperators = []string{OpEq, OpNe, OpIn, OpGt, OpGte, OpLt, OpLte}
+)
+
+var ErrInvalidFilter = Validation("invalid filter")
+
+// FilterFields maps each column an entity can be filtered on to the operators it allows
+type FilterFields map[string][]string
+
+// Filter is one condition on a column. Values holds the list for in, Value everything else.
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+	Values   []string
+}
+
+// Positive returns, for the negative filters ne and null=true, the filter that matches where they
+// do not, and true. Other filters come back as they are, with false.
+func Positive(filter Filter) (Filter, bool) {
+	switch {
+	case filter.Operator == OpNe:
+		filter.Operator = OpEq
+		return filter, true
+	case filter.Operator == OpNull && filter.Value == "true":
+		filter.Value = "false"
+		return filter, true
+	}
+	return filter, false
+}
+
+// parseFilterKey splits "field[op]" into field and op. A key without brackets is an eq filter.
+func parseFilterKey(key string) (string, string, bool) {
+	field, rest, found := strings.Cut(key, "[")
+	if !found {
+		return key, OpEq, false
+	}
+	op, ok := strings.CutSuffix(rest, "]")
+	if !ok {
+		return field, "", true
+	}
+	return field, op, true
+}
+
+// ParseFilters reads the filters for fields from the query string. Params that are not filters,
+// such as sortby or page, are skipped, while a bracketed param on an unknown field or with an
+// operator the field does not allow is an error.
+func ParseFilters(r *http.Request, fields FilterFields) ([]Filter, error) {
+	query := r.URL.Query()
+
+	// sorted so the same request always builds the same SQL
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []Filter
+	for _, key := range keys {
+		field, op, bracketed := parseFilterKey(key)
+		allowedOps, ok := fields[field]
+		if !ok {
+			if bracketed {
+				return nil, fmt.Errorf("%w: cannot filter on %q", ErrInvalidFilter, field)
+			}
+			continue
+		}
+		if !isAllowedField(op, allowedOps) {
+			return nil, fmt.Errorf("%w: operator %q is not allowed on %q", ErrInvalidFilter, op, field)
+		}
+
+		value := query.Get(key)
+		filter := Filter{Field: field, Operator: op, Value: value}
+		switch op {
+		case OpEq:
+			// an empty value means the filter was left blank
+			if value == "" {
+				continue
+			}
+		case OpIn:
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					filter.Values = append(filter.Values, v)
+				}
+			}
+			if len(filter.Values) == 0 {
+				return nil, fmt.Errorf("%w: %s[in] needs at least one value", ErrInvalidFilter, field)
+			}
+		case OpNull:
+			if value != "true" && value != "false" {
+				return nil, fmt.Errorf("%w: %s[null] must be true or false", ErrInvalidFilter, field)
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// escapeLike escapes the LIKE wildcards in value so it only matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// AddFilters appends a parameterized condition for every filter. Field names come from
+// the entity's FilterFields, never straight from the request.
+func AddFilters(query string, args []interface{}, filters []Filter) (string, []interface{}) {
+	for _, filter := range filters {
+		switch filter.Operator {
+		case OpNe:
+			query += " AND " + filter.Field + " <> ?"
+			args = append(args, filter.Value)
+		case OpLike:
+			// like matches anywhere in the column
+			query += " AND " + filter.Field + " LIKE ?"
+			args = append(args, "%"+escapeLike(filter.Value)+"%")
+		case OpIn:
+			query += " AND " + filter.Field + " IN (?" + strings.Repeat(", ?", len(filter.Values)-1) + ")"
+			for _, value := range filter.Values {
+				args = append(args, value)
+			}
+		case OpGt:
+			query += " AND " + filter.Field + " > ?"
+			args = append(args, filter.Value)
+		case OpGte:
+			query += " AND " + filter.Field + " >= ?"
+			args = append(args, filter.Value)
+		case OpLt:
+			query += " AND " + filter.Field + " < ?"
+			args = append(args, filter.Value)
+		case OpLte:
+			query += " AND " + filter.Field + " <= ?"
+			args = append(args, filter.Value)
+		case OpNull:
+			if filter.Value == "true" {
+				query += " AND " + filter.Field + " IS NULL"
+			} else {
+				query += " AND " + filter.Field + " IS NOT NULL"
+			}
+		default:
+			query += " AND " + filter.Field + " = ?"
+			args = append(args, filter.Value)
+		}
+	}
+	return query, args
+}
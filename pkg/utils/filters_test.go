@@ -0,0 +1,75 @@
+package utils_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"restapi/pkg/utils"
+	"testing"
+)
+
+var filterFields = utils.FilterFields{
+	"first_name": utils.TextOperators,
+	"class":      utils.TextOperators,
+	"id":         utils.NumberOperators,
+}
+
+func TestParseFilters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/teachers?sortby=id:asc&page=2&first_name[like]=Jo&class[in]=9A,%209B,&id[gte]=3&class[null]=false&id=&email=x", nil)
+	filters, err := utils.ParseFilters(r, filterFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sorted by key, with the blank id and the unknown email left out
+	want := []utils.Filter{
+		{Field: "class", Operator: utils.OpIn, Value: "9A, 9B,", Values: []string{"9A", "9B"}},
+		{Field: "class", Operator: utils.OpNull, Value: "false"},
+		{Field: "first_name", Operator: utils.OpLike, Value: "Jo"},
+		{Field: "id", Operator: utils.OpGte, Value: "3"},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("got %+v, want %+v", filters, want)
+	}
+
+	for _, query := range []string{
+		"email[eq]=x",
+		"first_name[gt]=J",
+		"id[like]=1",
+		"first_name[like=Jo",
+		"class[in]=,%20,",
+		"class[null]=yes",
+	} {
+		r := httptest.NewRequest("GET", "/teachers?"+query, nil)
+		_, err := utils.ParseFilters(r, filterFields)
+		if !errors.Is(err, utils.ErrInvalidFilter) {
+			t.Errorf("got %v for %s, want ErrInvalidFilter", err, query)
+		}
+	}
+}
+
+func TestAddFilters(t *testing.T) {
+	filters := []utils.Filter{
+		{Field: "first_name", Operator: utils.OpEq, Value: "Jo"},
+		{Field: "first_name", Operator: utils.OpNe, Value: "Al"},
+		{Field: "email", Operator: utils.OpLike, Value: `50%_off\`},
+		{Field: "class", Operator: utils.OpIn, Values: []string{"9A", "9B"}},
+		{Field: "id", Operator: utils.OpGt, Value: "1"},
+		{Field: "id", Operator: utils.OpLte, Value: "9"},
+		{Field: "capacity", Operator: utils.OpNull, Value: "true"},
+		{Field: "subject", Operator: utils.OpNull, Value: "false"},
+	}
+
+	query, args := utils.AddFilters("SELECT * FROM teachers WHERE 1=1", []interface{}{"deleted"}, filters)
+	wantQuery := "SELECT * FROM teachers WHERE 1=1 AND first_name = ? AND first_name <> ? AND email LIKE ? AND class IN (?, ?)" +
+		" AND id > ? AND id <= ? AND capacity IS NULL AND subject IS NOT NULL"
+	if query != wantQuery {
+		t.Errorf("got %s, want %s", query, wantQuery)
+	}
+
+	// the LIKE wildcards in the value only match themselves
+	wantArgs := []interface{}{"deleted", "Jo", "Al", `%50\%\_off\\%`, "9A", "9B", "1", "9"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got %q, want %q", args, wantArgs)
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ContextKey is the type of the keys the JWT middleware stores claims under
+type ContextKey string
+
+// errNoJWTSecret is returned instead of signing with an empty key, which anyone could forge
+var errNoJWTSecret = errors.New("JWT_SECRET is unset")
+
+// CheckJWTSecret fails when there is no key to sign login tokens with, so the server can refuse to start
+func CheckJWTSecret() error {
+	if os.Getenv("JWT_SECRET") == "" {
+		return errNoJWTSecret
+	}
+	return nil
+}
+
+// TokenLifetime is how long a login token is valid, JWT_EXPIRES_IN or 15 minutes when it is unset
+func TokenLifetime() (time.Duration, error) {
+	jwtExpiresIn := os.Getenv("JWT_EXPIRES_IN")
+	if jwtExpiresIn == "" {
+		return 15 * time.Minute, nil
+	}
+	return time.ParseDuration(jwtExpiresIn)
+}
+
+// SignToken returns a login token for the exec and the time it expires at, for the cookie
+// carrying it to expire with it
+func SignToken(userId int, username, email, role string) (string, time.Time, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return "", time.Time{}, Internal(errNoJWTSecret, "internal error")
+	}
+
+	lifetime, err := TokenLifetime()
+	if err != nil {
+		return "", time.Time{}, Internal(err, "internal error")
+	}
+	expiresAt := time.Now().Add(lifetime)
+
+	claims := jwt.MapClaims{
+		"uid":   userId,
+		"user":  username,
+		"email": email,
+		"role":  role,
+		"exp":   jwt.NewNumericDate(expiresAt),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		return "", time.Time{}, Internal(err, "internal error")
+	}
+	return signedToken, expiresAt, nil
+}
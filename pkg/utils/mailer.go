@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends mail through an SMTP server. Locally this can point at a
+// stand-in like MailHog (localhost:1025) which needs no auth.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", m.From, to, subject, body)
+	err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(msg))
+	if err != nil {
+		return Internal(err, "failed to send email")
+	}
+	return nil
+}
+
+// FileMailer writes each email to a file in Dir instead of sending it
+type FileMailer struct {
+	Dir string
+}
+
+func (m FileMailer) Send(to, subject, body string) error {
+	err := os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return Internal(err, "failed to send email")
+	}
+
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), strings.ReplaceAll(to, "@", "_at_"))
+	msg := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", to, subject, body)
+	err = os.WriteFile(filepath.Join(m.Dir, name), []byte(msg), 0o600)
+	if err != nil {
+		return Internal(err, "failed to send email")
+	}
+	return nil
+}
+
+// NewMailer picks the mailer from MAIL_DRIVER ("smtp" or "file"), defaulting to smtp on localhost:1025
+func NewMailer() Mailer {
+	if os.Getenv("MAIL_DRIVER") == "file" {
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "restapi_mail")
+		}
+		return FileMailer{Dir: dir}
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "1025"
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "schooladmin@school.com"
+	}
+	return SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPageLimit = 20
+	// MaxPageLimit caps the limit query param so a single request can't pull the whole table
+	MaxPageLimit = 100
+)
+
+// ParsePagination reads the page and limit query params, falling back to the first page
+// with the default limit and capping limit at MaxPageLimit
+func ParsePagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return page, limit
+}
+
+func AddPagination(query string, args []interface{}, page, limit int) (string, []interface{}) {
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, max(page-1, 0)*limit)
+	}
+	return query, args
+}
+
+// TotalPages is the number of pages of size limit needed for total items
+func TotalPages(total, limit int) int {
+	if limit < 1 {
+		return 1
+	}
+	return (total + limit - 1) / limit
+}
+
+// LinkHeader builds an RFC 8288 Link header with first, prev, next and last page links,
+// keeping the rest of the request's query string
+func LinkHeader(r *http.Request, page, limit, totalPages int) string {
+	lastPage := totalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	pageURL := func(p int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(p))
+		query.Set("limit", strconv.Itoa(limit))
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(1))}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(page-1, lastPage))))
+	}
+	if page < lastPage {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(lastPage)))
+	return strings.Join(links, ", ")
+}
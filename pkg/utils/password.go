@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// HashPassword hashes the password with argon2id and returns "salt.hash", both base64 encoded
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", Validation("please enter password")
+	}
+
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", Internal(errors.New("failed to generate salt"), "error adding data")
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+	saltBase64 := base64.StdEncoding.EncodeToString(salt)
+	hashBase64 := base64.StdEncoding.EncodeToString(hash)
+
+	encodedHash := fmt.Sprintf("%s.%s", saltBase64, hashBase64)
+	return encodedHash, nil
+}
+
+// VerifyPassword checks password against an encoded hash produced by HashPassword
+func VerifyPassword(password, encodedHash string) error {
+	parts := strings.Split(encodedHash, ".")
+	if len(parts) != 2 {
+		return Internal(errors.New("invalid encoded hash format"), "internal server error")
+	}
+
+	saltBase64 := parts[0]
+	hashedPasswordBase64 := parts[1]
+
+	salt, err := base64.StdEncoding.DecodeString(saltBase64)
+	if err != nil {
+		return Internal(err, "internal server error")
+	}
+
+	hashedPassword, err := base64.StdEncoding.DecodeString(hashedPasswordBase64)
+	if err != nil {
+		return Internal(err, "internal server error")
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+
+	if len(hash) != len(hashedPassword) || subtle.ConstantTimeCompare(hash, hashedPassword) != 1 {
+		return Unauthorized("incorrect username or password")
+	}
+	return nil
+}
+
+// GenerateResetToken returns a random token to send to the user and the sha256 hash to store in its place
+func GenerateResetToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", "", Internal(err, "failed to generate reset token")
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+	return token, HashResetToken(token), nil
+}
+
+func HashResetToken(token string) string {
+	hashedToken := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hashedToken[:])
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// patterns are the named formats the pattern=<name> rule can check
+var patterns = map[string]*regexp.Regexp{
+	// a grade from 1 to 12 followed by the section letter, e.g. 9A
+	"class": regexp.MustCompile(`^([1-9]|1[0-2])[A-Za-z]$`),
+}
+
+// FieldError is one failed rule of a validate tag. Index is the position of the item
+// in a bulk payload and is left out for single items.
+type FieldError struct {
+	Index   *int   `json:"index,omitempty"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Validate checks every field of model against the rules of its validate tag, e.g.
+// `validate:"required,email,max=100"`, and returns all failures, named by json tag.
+//
+// Rules: required, email, min=n and max=n (string length, or value of an int), pattern=<name> and oneof=a|b|c.
+func Validate(model interface{}) []FieldError {
+	return validate(model, nil)
+}
+
+// ValidateFields is Validate limited to the named json fields, for partial updates
+func ValidateFields(model interface{}, fields []string) []FieldError {
+	if fields == nil {
+		fields = []string{}
+	}
+	return validate(model, fields)
+}
+
+func validate(model interface{}, only []string) []FieldError {
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	valType := val.Type()
+
+	var fieldErrors []FieldError
+	for i := 0; i < val.NumField(); i++ {
+		tag := valType.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := strings.TrimSuffix(valType.Field(i).Tag.Get("json"), ",omitempty")
+		if only != nil && !ContainsString(only, name) {
+			continue
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			message := checkRule(val.Field(i), rule)
+			if message != "" {
+				ruleName, _, _ := strings.Cut(rule, "=")
+				fieldErrors = append(fieldErrors, FieldError{Field: name, Rule: ruleName, Message: message})
+			}
+		}
+	}
+	return fieldErrors
+}
+
+// checkRule returns why value breaks rule, or "" when it passes.
+// Rules other than required pass on an empty value. A pointer is checked as the value it points
+// to, where a 0 counts as set, and a nil pointer is empty.
+func checkRule(value reflect.Value, rule string) string {
+	name, param, _ := strings.Cut(rule, "=")
+	set := false
+	if value.Kind() == reflect.Ptr && !value.IsNil() {
+		value, set = value.Elem(), true
+	}
+
+	if name == "required" {
+		if !set && (value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "")) {
+			return "is required"
+		}
+		return ""
+	}
+
+	if value.CanInt() {
+		return checkNumber(value.Int(), set, name, param)
+	}
+	if value.Kind() != reflect.String || value.String() == "" {
+		return ""
+	}
+	str := value.String()
+
+	switch name {
+	case "email":
+		addr, err := mail.ParseAddress(str)
+		if err != nil || addr.Address != str {
+			return "must be a valid email address"
+		}
+	case "min":
+		n, _ := strconv.Atoi(param)
+		if utf8.RuneCountInString(str) < n {
+			return fmt.Sprintf("must be at least %d characters long", n)
+		}
+	case "max":
+		n, _ := strconv.Atoi(param)
+		if utf8.RuneCountInString(str) > n {
+			return fmt.Sprintf("must be at most %d characters long", n)
+		}
+	case "pattern":
+		pattern, ok := patterns[param]
+		if ok && !pattern.MatchString(str) {
+			return fmt.Sprintf("is not a valid %s", param)
+		}
+	case "oneof":
+		options := strings.Split(param, "|")
+		if !ContainsString(options, str) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	}
+	return ""
+}
+
+// AtIndex marks fieldErrors as belonging to item index of a bulk payload
+func AtIndex(fieldErrors []FieldError, index int) []FieldError {
+	for i := range fieldErrors {
+		fieldErrors[i].Index = &index
+	}
+	return fieldErrors
+}
+
+// ValidationFailed reports fieldErrors as a single validation error, or returns nil when there are none
+func ValidationFailed(fieldErrors []FieldError) error {
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return Validation(fmt.Sprintf("%d invalid field(s)", len(fieldErrors))).With("errors", fieldErrors)
+}
+
+// checkNumber applies min and max to an integer. Unless set, 0 counts as empty and passes.
+func checkNumber(n int64, set bool, name, param string) string {
+	limit, _ := strconv.ParseInt(param, 10, 64)
+	switch {
+	case n == 0 && !set:
+	case name == "min" && n < limit:
+		return fmt.Sprintf("must be at least %d", limit)
+	case name == "max" && n > limit:
+		return fmt.Sprintf("must be at most %d", limit)
+	}
+	return ""
+}